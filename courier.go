@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"eve-tour/courier"
+	"eve-tour/esi"
+	"eve-tour/route"
+	"eve-tour/universe"
+)
+
+const courierUsage = `usage: eve-tour courier [-contracts FILE | -regions NAMES] [-capacity M3] [-collateral ISK]
+
+Picks courier contracts and plans the pickups and deliveries earning the most ISK per jump, then uploads the route.
+Contracts are read from a file or downloaded from the public contracts of regions.
+`
+
+func runCourier(args []string) error {
+	fs := flag.NewFlagSet("courier", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), courierUsage)
+		fs.PrintDefaults()
+	}
+	var contractsFile string
+	fs.StringVar(&contractsFile, "contracts", "", "JSON file of contracts, ESI's public contracts as downloaded or {\"from\", \"to\", \"volume\", \"reward\", \"collateral\"} objects naming systems.")
+	var regions string
+	fs.StringVar(&regions, "regions", "", "Download the public courier contracts of theses regions, separated by commas.")
+	var limits courier.Limits
+	fs.Float64Var(&limits.Capacity, "capacity", 0, "Cargo capacity in m³, 0 means no limit.")
+	fs.Float64Var(&limits.Collateral, "collateral", 0, "Most collateral at stake at once in ISK, 0 means no limit.")
+	fs.IntVar(&limits.MaxJumps, "max-jumps", 0, "Longest tour in jumps, 0 means no limit.")
+	var countCostOfStartSystem bool
+	fs.BoolVar(&countCostOfStartSystem, "start", false, "Start from your current system.")
+	client := esi.NewClient()
+	fs.StringVar(&client.BaseURL, "esi-url", client.BaseURL, "Base URL of the ESI API.")
+	fs.StringVar(&client.SSOURL, "sso-url", client.SSOURL, "Base URL of the EVE SSO.")
+	fs.Parse(args)
+	if (contractsFile == "") == (regions == "") {
+		fs.Usage()
+		return fmt.Errorf("need one of -contracts or -regions")
+	}
+
+	g, err := universe.LoadOrCreate(client, universe.CrawlOptions{Workers: 8, Log: logger})
+	if err != nil {
+		return fmt.Errorf("failed to load graph: %w", err)
+	}
+	// Structures let us place contracts to and from public citadels, only if they were fetched before, see -structures.
+	structures, ok, err := universe.LoadStructures(universe.StructuresFile)
+	if err != nil {
+		return err
+	}
+	if ok {
+		g.AddStructures(structures)
+	}
+
+	var contracts []courier.Contract
+	var skipped int
+	if contractsFile != "" {
+		contracts, skipped, err = courier.ReadContracts(g, contractsFile, time.Now())
+		if err != nil {
+			return err
+		}
+	} else {
+		raw, err := fetchContracts(client, route.ParseNames(regions))
+		if err != nil {
+			return err
+		}
+		contracts, skipped = courier.Resolve(g, raw, time.Now())
+	}
+	fmt.Println(len(contracts), "courier contracts,", skipped, "skipped")
+
+	var token string
+	var start uint32
+	if countCostOfStartSystem {
+		token, start, err = currentSystem(client)
+		if err != nil {
+			return err
+		}
+	}
+
+	plan := courier.Solve(g, contracts, start, limits)
+	if len(plan.Stops) == 0 {
+		return fmt.Errorf("no contract fits the limits")
+	}
+	var systems []uint32
+	for _, s := range plan.Stops {
+		c := contracts[s.Contract]
+		verb, system := "deliver", c.To
+		if s.Pickup {
+			verb, system = "pickup ", c.From
+		}
+		fmt.Printf("%s %d\t%s -> %s\t%.0f m³\t%s ISK\t%s collateral\n",
+			verb, c.ID, g.Nodes[c.From].Name, g.Nodes[c.To].Name, c.Volume, isk(c.Reward), isk(c.Collateral))
+		if len(systems) == 0 || systems[len(systems)-1] != system {
+			systems = append(systems, system)
+		}
+	}
+	fmt.Printf("%d contracts, %d jumps, %s ISK, %s ISK per jump\n", len(plan.Contracts()), plan.Jumps, isk(plan.Reward), isk(plan.IskPerJump()))
+
+	return uploadStops(client, token, g, systems)
+}
+
+// fetchContracts downloads the public contracts of the regions named in names.
+func fetchContracts(client *esi.Client, names map[string]struct{}) ([]esi.Contract, error) {
+	regions, err := lookupRegions(client, names)
+	if err != nil {
+		return nil, err
+	}
+	var contracts []esi.Contract
+	for _, id := range slices.Sorted(maps.Keys(regions)) {
+		c, err := client.PublicContracts(id)
+		if err != nil {
+			return nil, fmt.Errorf("fetching contracts of %s: %w", regions[id], err)
+		}
+		contracts = append(contracts, c...)
+	}
+	return contracts, nil
+}
+
+// lookupRegions finds the IDs of the regions named in names, the graph only knows their names.
+func lookupRegions(client *esi.Client, names map[string]struct{}) (map[uint32]string, error) {
+	ids, err := client.Regions()
+	if err != nil {
+		return nil, fmt.Errorf("listing regions: %w", err)
+	}
+	missing := maps.Clone(names)
+	regions := make(map[uint32]string)
+	for _, id := range ids {
+		r, err := client.Region(id)
+		if err != nil {
+			return nil, fmt.Errorf("fetching region %d: %w", id, err)
+		}
+		if _, ok := names[strings.ToLower(r.Name)]; ok {
+			regions[id] = r.Name
+			delete(missing, strings.ToLower(r.Name))
+		}
+	}
+	for name := range missing {
+		return nil, fmt.Errorf("unknown region %q", name)
+	}
+	return regions, nil
+}
+
+// currentSystem logs in and returns where the character is.
+func currentSystem(client *esi.Client) (token string, system uint32, err error) {
+	token, characterId, err := client.Login()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to grab user token: %w", err)
+	}
+	system, err = client.Location(token, characterId)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get location: %w", err)
+	}
+	return token, system, nil
+}
+
+// uploadStops writes the systems of stops to output.txt and sends them to the autopilot, token is empty if we didn't log in yet.
+func uploadStops(client *esi.Client, token string, g universe.Graph, systems []uint32) error {
+	err := writeOutput(g, systems, nil)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	if token == "" {
+		token, _, err = client.Login()
+		if err != nil {
+			return fmt.Errorf("failed to grab user token: %w", err)
+		}
+	}
+	err = client.AddWaypoints(token, systems)
+	if err != nil {
+		return fmt.Errorf("failed to add waypoints to UI: %w", err)
+	}
+	return nil
+}
+
+// isk formats an amount with a metric suffix, like the game does.
+func isk(v float64) string {
+	switch {
+	case v >= 1e9:
+		return fmt.Sprintf("%.2fB", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("%.2fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.2fk", v/1e3)
+	}
+	return fmt.Sprintf("%.0f", v)
+}
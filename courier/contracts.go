@@ -0,0 +1,124 @@
+// Package courier picks courier contracts and plans the pickup and delivery tour carrying them.
+package courier
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"eve-tour/esi"
+	"eve-tour/universe"
+)
+
+// Contract is a courier contract between two reachable systems.
+type Contract struct {
+	ID uint32
+	// From and To are systems, FromLocation and ToLocation the station or structure in them, 0 if the contract only named the system.
+	From, To                 uint32
+	FromLocation, ToLocation uint64
+	// Volume is in m³, Reward and Collateral in ISK.
+	Volume, Reward, Collateral float64
+}
+
+// Locations maps every known station and public structure to its system, structures need [universe.Graph.AddStructures] first.
+func Locations(g universe.Graph) map[uint64]uint32 {
+	locations := make(map[uint64]uint32)
+	for id, s := range g.Nodes {
+		for _, st := range s.Stations {
+			locations[uint64(st)] = id
+		}
+		for _, st := range s.Structures {
+			locations[st.ID] = id
+		}
+	}
+	return locations
+}
+
+// Resolve keeps the courier contracts going between reachable locations that haven't expired at now.
+// Contracts starting or ending in private structures can't be placed and are skipped along with the rest.
+func Resolve(g universe.Graph, contracts []esi.Contract, now time.Time) (resolved []Contract, skipped int) {
+	locations := Locations(g)
+	for _, c := range contracts {
+		from, okFrom := locations[c.StartLocationID]
+		to, okTo := locations[c.EndLocationID]
+		if c.Type != "courier" || !c.DateExpired.IsZero() && c.DateExpired.Before(now) || !okFrom || !okTo || !reachable(g, from, to) {
+			skipped++
+			continue
+		}
+		resolved = append(resolved, Contract{
+			ID:           c.ContractID,
+			From:         from,
+			To:           to,
+			FromLocation: c.StartLocationID,
+			ToLocation:   c.EndLocationID,
+			Volume:       c.Volume,
+			Reward:       c.Reward,
+			Collateral:   c.Collateral,
+		})
+	}
+	return resolved, skipped
+}
+
+func reachable(g universe.Graph, systems ...uint32) bool {
+	for _, id := range systems {
+		if _, ok := g.IdsToMatrixIndexes[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// fileContract is a contract as saved from ESI, from and to name the systems of hand written ones instead of the locations.
+type fileContract struct {
+	esi.Contract
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ReadContracts reads a JSON array of contracts, either ESI's public contracts as downloaded or hand written ones like
+//
+//	{"contract_id": 1, "from": "Jita", "to": "Amarr", "volume": 12000, "reward": 9000000, "collateral": 150000000}
+//
+// Entries without a type are taken as couriers.
+func ReadContracts(g universe.Graph, path string, now time.Time) (contracts []Contract, skipped int, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading contracts: %w", err)
+	}
+	var entries []fileContract
+	err = json.Unmarshal(b, &entries)
+	if err != nil {
+		return nil, 0, fmt.Errorf("decoding contracts: %w", err)
+	}
+
+	var fromESI []esi.Contract
+	for _, e := range entries {
+		if e.Type == "" {
+			e.Type = "courier"
+		}
+		if e.From == "" && e.To == "" {
+			fromESI = append(fromESI, e.Contract)
+			continue
+		}
+		from, okFrom := g.Lookup(e.From)
+		to, okTo := g.Lookup(e.To)
+		if !okFrom || !okTo {
+			return nil, 0, fmt.Errorf("contract %d: unknown system %q or %q", e.ContractID, e.From, e.To)
+		}
+		if e.Type != "courier" || !e.DateExpired.IsZero() && e.DateExpired.Before(now) || !reachable(g, from, to) {
+			skipped++
+			continue
+		}
+		contracts = append(contracts, Contract{
+			ID:         e.ContractID,
+			From:       from,
+			To:         to,
+			Volume:     e.Volume,
+			Reward:     e.Reward,
+			Collateral: e.Collateral,
+		})
+	}
+	resolved, n := Resolve(g, fromESI, now)
+	return append(contracts, resolved...), skipped + n, nil
+}
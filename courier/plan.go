@@ -0,0 +1,278 @@
+package courier
+
+import (
+	"eve-tour/distance"
+	"eve-tour/universe"
+)
+
+// Limits bound what we carry at once, zero means no limit.
+type Limits struct {
+	// Capacity is the cargo hold in m³.
+	Capacity float64
+	// Collateral is the ISK we are willing to have at stake at once.
+	Collateral float64
+	// MaxJumps caps the length of the whole tour.
+	MaxJumps int
+}
+
+// Stop picks up or delivers one contract.
+type Stop struct {
+	// Contract indexes the contracts given to [Solve].
+	Contract int
+	Pickup   bool
+}
+
+// Plan is an ordered list of pickups and deliveries, each accepted contract is picked up before it is delivered.
+type Plan struct {
+	Stops  []Stop
+	Jumps  int
+	Reward float64
+}
+
+// IskPerJump is what the plan optimizes, a tour of zero jumps counts as one.
+func (p Plan) IskPerJump() float64 {
+	return p.Reward / float64(max(p.Jumps, 1))
+}
+
+// Contracts returns the indexes of the accepted contracts, in pickup order.
+func (p Plan) Contracts() []int {
+	var accepted []int
+	for _, s := range p.Stops {
+		if s.Pickup {
+			accepted = append(accepted, s.Contract)
+		}
+	}
+	return accepted
+}
+
+// planner holds one tour being improved, jumps are read from the graph's matrix.
+type planner struct {
+	contracts []Contract
+	limits    Limits
+	matrix    distance.D2
+	// from and to are the matrix indexes of the contracts' systems.
+	from, to []uint
+	// start is the matrix index of the current location, valid if hasStart.
+	start    uint
+	hasStart bool
+
+	stops  []Stop
+	jumps  int
+	reward float64
+}
+
+func (p *planner) node(s Stop) uint {
+	if s.Pickup {
+		return p.from[s.Contract]
+	}
+	return p.to[s.Contract]
+}
+
+// dist is the jump count between two positions of the tour, -1 is the start and len(stops) the end which is free to reach.
+func (p *planner) dist(i, j int) int {
+	if j == len(p.stops) {
+		return 0
+	}
+	if i < 0 {
+		if !p.hasStart {
+			return 0
+		}
+		return int(p.matrix.At(p.start, p.node(p.stops[j])))
+	}
+	return int(p.matrix.At(p.node(p.stops[i]), p.node(p.stops[j])))
+}
+
+// distTo is the jump count from position i of the tour to matrix index n, or from n to position i if reverse.
+func (p *planner) distTo(i int, n uint, reverse bool) int {
+	if i == len(p.stops) {
+		return 0
+	}
+	if i < 0 {
+		if !p.hasStart {
+			return 0
+		}
+		return int(p.matrix.At(p.start, n))
+	}
+	if reverse {
+		return int(p.matrix.At(n, p.node(p.stops[i])))
+	}
+	return int(p.matrix.At(p.node(p.stops[i]), n))
+}
+
+// insertion places contract k's pickup before position i and its delivery before position j of the current tour.
+type insertion struct {
+	contract, i, j int
+	delta          int
+}
+
+// bestInsertion returns the cheapest feasible way to add contract k, ok is false if there is none.
+func (p *planner) bestInsertion(k int) (best insertion, ok bool) {
+	c := p.contracts[k]
+	// load and stake after each stop, the contract rides along from its pickup to its delivery
+	load, stake := make([]float64, len(p.stops)), make([]float64, len(p.stops))
+	var l, s float64
+	for i, st := range p.stops {
+		sign := -1.0
+		if st.Pickup {
+			sign = 1
+		}
+		l += sign * p.contracts[st.Contract].Volume
+		s += sign * p.contracts[st.Contract].Collateral
+		load[i], stake[i] = l, s
+	}
+	fits := func(i int) bool {
+		var l, s float64
+		if i >= 0 {
+			l, s = load[i], stake[i]
+		}
+		return (p.limits.Capacity == 0 || l+c.Volume <= p.limits.Capacity) &&
+			(p.limits.Collateral == 0 || s+c.Collateral <= p.limits.Collateral)
+	}
+
+	from, to := p.from[k], p.to[k]
+	direct := int(p.matrix.At(from, to))
+	for i := 0; i <= len(p.stops); i++ {
+		if !fits(i - 1) {
+			continue
+		}
+		pickupDelta := p.distTo(i-1, from, false) + p.distTo(i, from, true) - p.dist(i-1, i)
+		for j := i; j <= len(p.stops); j++ {
+			if j > i && !fits(j-1) {
+				break // the contract would have to ride through a full hold
+			}
+			var delta int
+			if j == i {
+				delta = p.distTo(i-1, from, false) + direct + p.distTo(i, to, true) - p.dist(i-1, i)
+			} else {
+				delta = pickupDelta + p.distTo(j-1, to, false) + p.distTo(j, to, true) - p.dist(j-1, j)
+			}
+			if p.limits.MaxJumps != 0 && p.jumps+delta > p.limits.MaxJumps {
+				continue
+			}
+			if !ok || delta < best.delta {
+				best, ok = insertion{contract: k, i: i, j: j, delta: delta}, true
+			}
+		}
+	}
+	return best, ok
+}
+
+func (p *planner) insert(in insertion) {
+	// insert the delivery first so i still points at the right place
+	p.stops = append(p.stops[:in.j], append([]Stop{{Contract: in.contract}}, p.stops[in.j:]...)...)
+	p.stops = append(p.stops[:in.i], append([]Stop{{Contract: in.contract, Pickup: true}}, p.stops[in.i:]...)...)
+	p.reward += p.contracts[in.contract].Reward
+	p.jumps = p.length()
+}
+
+func (p *planner) remove(k int) {
+	stops := p.stops[:0]
+	for _, s := range p.stops {
+		if s.Contract != k {
+			stops = append(stops, s)
+		}
+	}
+	p.stops = stops
+	p.reward -= p.contracts[k].Reward
+	p.jumps = p.length()
+}
+
+func (p *planner) length() int {
+	var jumps int
+	for i := range p.stops {
+		jumps += p.dist(i-1, i)
+	}
+	return jumps
+}
+
+func ratio(reward float64, jumps int) float64 {
+	return reward / float64(max(jumps, 1))
+}
+
+// Solve chooses contracts and orders their pickups and deliveries to maximize ISK per jump within limits.
+// start is the system we set off from, 0 lets the tour begin at the first pickup.
+//
+// It's a greedy heuristic: contracts are inserted at their cheapest place while that raises ISK per jump,
+// then each one is taken out and put back where it's cheapest, or dropped if the tour is better off without it, until nothing changes.
+func Solve(g universe.Graph, contracts []Contract, start uint32, limits Limits) Plan {
+	p := &planner{
+		contracts: contracts,
+		limits:    limits,
+		matrix:    g.Matrix,
+		from:      make([]uint, len(contracts)),
+		to:        make([]uint, len(contracts)),
+	}
+	if start != 0 {
+		p.start, p.hasStart = g.IdsToMatrixIndexes[start]
+	}
+	var candidates []int
+	for k, c := range contracts {
+		p.from[k], p.to[k] = g.IdsToMatrixIndexes[c.From], g.IdsToMatrixIndexes[c.To]
+		if limits.Capacity != 0 && c.Volume > limits.Capacity || limits.Collateral != 0 && c.Collateral > limits.Collateral {
+			continue
+		}
+		if p.matrix.At(p.from[k], p.to[k]) == distance.Infinity || p.hasStart && p.matrix.At(p.start, p.from[k]) == distance.Infinity {
+			continue
+		}
+		candidates = append(candidates, k)
+	}
+
+	accepted := make(map[int]bool)
+	for changed := true; changed; {
+		changed = false
+
+		// add contracts while they raise the ratio
+		for {
+			var best insertion
+			var found bool
+			bestRatio := ratio(p.reward, p.jumps)
+			if len(p.stops) == 0 {
+				bestRatio = 0
+			}
+			for _, k := range candidates {
+				if accepted[k] {
+					continue
+				}
+				in, ok := p.bestInsertion(k)
+				if !ok {
+					continue
+				}
+				if r := ratio(p.reward+contracts[k].Reward, p.jumps+in.delta); r > bestRatio {
+					best, found, bestRatio = in, true, r
+				}
+			}
+			if !found {
+				break
+			}
+			p.insert(best)
+			accepted[best.contract] = true
+			changed = true
+		}
+
+		// move or drop contracts
+		for _, k := range candidates {
+			if !accepted[k] {
+				continue
+			}
+			before := ratio(p.reward, p.jumps)
+			saved := append([]Stop(nil), p.stops...)
+			p.remove(k)
+			if len(p.stops) > 0 && ratio(p.reward, p.jumps) > before {
+				delete(accepted, k)
+				changed = true
+				continue
+			}
+			in, ok := p.bestInsertion(k)
+			if ok && ratio(p.reward+contracts[k].Reward, p.jumps+in.delta) > before {
+				p.insert(in)
+				changed = true
+				continue
+			}
+			p.stops = saved
+			p.reward += contracts[k].Reward
+			p.jumps = p.length()
+		}
+	}
+
+	return Plan{Stops: p.stops, Jumps: p.jumps, Reward: p.reward}
+}
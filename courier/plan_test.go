@@ -0,0 +1,207 @@
+package courier
+
+import (
+	"slices"
+	"testing"
+
+	"eve-tour/distance"
+	"eve-tour/universe"
+)
+
+// line is a graph of systems 1 to n in a row, system i is i-1 jumps from the first.
+func line(n int) universe.Graph {
+	g := universe.Graph{
+		Nodes:              make(map[uint32]universe.System),
+		IdsToMatrixIndexes: make(map[uint32]uint),
+		Matrix:             distance.NewD2(uint(n)),
+	}
+	for i := range n {
+		id := uint32(i + 1)
+		g.Nodes[id] = universe.System{}
+		g.IdsToMatrixIndexes[id] = uint(i)
+		g.MatrixIndexesToIds = append(g.MatrixIndexesToIds, id)
+		for j := range n {
+			g.Matrix.Set(uint(i), uint(j), uint8(max(i-j, j-i)))
+		}
+	}
+	return g
+}
+
+func TestSolve(t *testing.T) {
+	g := line(10)
+	tests := []struct {
+		name      string
+		contracts []Contract
+		start     uint32
+		limits    Limits
+		// accepted is in pickup order
+		accepted []int
+		jumps    int
+	}{
+		{
+			name:      "pickup before delivery",
+			contracts: []Contract{{From: 3, To: 1, Reward: 10}},
+			accepted:  []int{0},
+			jumps:     2,
+		},
+		{
+			name:      "from the start",
+			contracts: []Contract{{From: 3, To: 1, Reward: 10}},
+			start:     5,
+			accepted:  []int{0},
+			jumps:     4,
+		},
+		{
+			name: "riding along",
+			contracts: []Contract{
+				{From: 1, To: 5, Volume: 5, Collateral: 100, Reward: 100},
+				{From: 2, To: 4, Volume: 5, Collateral: 100, Reward: 100},
+			},
+			start:    1,
+			limits:   Limits{Capacity: 10, Collateral: 200},
+			accepted: []int{0, 1},
+			jumps:    4,
+		},
+		{
+			name: "hold full mid ride",
+			contracts: []Contract{
+				{From: 1, To: 5, Volume: 6, Reward: 200},
+				{From: 2, To: 4, Volume: 6, Reward: 100},
+			},
+			start:    1,
+			limits:   Limits{Capacity: 10},
+			accepted: []int{0},
+			jumps:    4,
+		},
+		{
+			name: "collateral at stake mid ride",
+			contracts: []Contract{
+				{From: 1, To: 5, Collateral: 100, Reward: 200},
+				{From: 2, To: 4, Collateral: 100, Reward: 100},
+			},
+			start:    1,
+			limits:   Limits{Collateral: 150},
+			accepted: []int{0},
+			jumps:    4,
+		},
+		{
+			name: "too big on its own",
+			contracts: []Contract{
+				{From: 1, To: 2, Volume: 20, Reward: 1000},
+				{From: 1, To: 2, Collateral: 1e9, Reward: 1000},
+				{From: 1, To: 3, Reward: 10},
+			},
+			start:    1,
+			limits:   Limits{Capacity: 10, Collateral: 1e6},
+			accepted: []int{2},
+			jumps:    2,
+		},
+		{
+			name: "jump limit",
+			contracts: []Contract{
+				{From: 1, To: 8, Reward: 1000},
+				{From: 1, To: 3, Reward: 10},
+			},
+			start:    1,
+			limits:   Limits{MaxJumps: 5},
+			accepted: []int{1},
+			jumps:    2,
+		},
+		{
+			// 100 ISK a jump alone, 500 over 8 jumps with the long one
+			name: "best ratio",
+			contracts: []Contract{
+				{From: 1, To: 2, Reward: 100},
+				{From: 1, To: 9, Reward: 400},
+			},
+			start:    1,
+			accepted: []int{0},
+			jumps:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := Solve(g, tt.contracts, tt.start, tt.limits)
+			if got := plan.Contracts(); !slices.Equal(got, tt.accepted) {
+				t.Fatalf("accepted %v, want %v, stops %+v", got, tt.accepted, plan.Stops)
+			}
+			if plan.Jumps != tt.jumps {
+				t.Errorf("%d jumps, want %d, stops %+v", plan.Jumps, tt.jumps, plan.Stops)
+			}
+
+			var reward, load, stake float64
+			jumps := 0
+			at, hasStart := g.IdsToMatrixIndexes[tt.start]
+			picked := make(map[int]bool)
+			for _, s := range plan.Stops {
+				c := tt.contracts[s.Contract]
+				system := c.To
+				if s.Pickup {
+					system = c.From
+					picked[s.Contract] = true
+					load += c.Volume
+					stake += c.Collateral
+					reward += c.Reward
+				} else {
+					if !picked[s.Contract] {
+						t.Fatalf("contract %d delivered before its pickup: %+v", s.Contract, plan.Stops)
+					}
+					load -= c.Volume
+					stake -= c.Collateral
+				}
+				if tt.limits.Capacity != 0 && load > tt.limits.Capacity || tt.limits.Collateral != 0 && stake > tt.limits.Collateral {
+					t.Errorf("carrying %v m³ and %v ISK of collateral at %+v, over %+v", load, stake, s, tt.limits)
+				}
+				next := g.IdsToMatrixIndexes[system]
+				if hasStart {
+					jumps += int(g.Matrix.At(at, next))
+				}
+				at, hasStart = next, true
+			}
+			if jumps != plan.Jumps || reward != plan.Reward {
+				t.Errorf("plan says %d jumps for %v ISK, the stops add up to %d for %v", plan.Jumps, plan.Reward, jumps, reward)
+			}
+		})
+	}
+}
+
+func TestBestInsertion(t *testing.T) {
+	g := line(10)
+	contracts := []Contract{
+		{From: 1, To: 5, Volume: 6, Collateral: 100},
+		{From: 2, To: 4, Volume: 6, Collateral: 100},
+	}
+	tests := []struct {
+		name   string
+		limits Limits
+		ok     bool
+		want   insertion
+	}{
+		{name: "rides along", ok: true, want: insertion{contract: 1, i: 1, j: 1, delta: 0}},
+		{name: "hold full", limits: Limits{Capacity: 10}, ok: true, want: insertion{contract: 1, i: 2, j: 2, delta: 5}},
+		{name: "collateral", limits: Limits{Collateral: 150}, ok: true, want: insertion{contract: 1, i: 2, j: 2, delta: 5}},
+		{name: "jump limit", limits: Limits{Capacity: 10, MaxJumps: 8}},
+		{name: "jump limit on the way", limits: Limits{MaxJumps: 4}, ok: true, want: insertion{contract: 1, i: 1, j: 1, delta: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &planner{
+				contracts: contracts,
+				limits:    tt.limits,
+				matrix:    g.Matrix,
+				from:      []uint{0, 1},
+				to:        []uint{4, 3},
+				start:     0,
+				hasStart:  true,
+			}
+			p.insert(insertion{contract: 0})
+			if p.jumps != 4 {
+				t.Fatalf("first contract takes %d jumps, want 4", p.jumps)
+			}
+			got, ok := p.bestInsertion(1)
+			if ok != tt.ok || ok && got != tt.want {
+				t.Errorf("bestInsertion = %+v, %v, want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
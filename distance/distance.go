@@ -0,0 +1,239 @@
+// Package distance holds the dense distance matrices used by every solver.
+package distance
+
+import (
+	"container/heap"
+	"log"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Weight is the element type of a [Matrix].
+// uint8 is plenty for jump counts, weighted costs such as seconds of travel need the wider ones.
+type Weight interface {
+	~uint8 | ~uint16 | ~uint32
+}
+
+// Infinity marks unreachable pairs in a [D2].
+const Infinity = ^uint8(0)
+
+// Max is the largest value of T, it marks unreachable pairs.
+func Max[T Weight]() T {
+	return ^T(0)
+}
+
+// Matrix is a dense, row-major, square matrix of costs.
+type Matrix[T Weight] struct {
+	RowSize uint
+	Arr     []T
+}
+
+// D2 is the compact matrix of jump counts.
+type D2 = Matrix[uint8] // using uint8 for distances since the longest are in the <~100 range and this makes the full dataset fit in the L3 cache of my CPU.
+
+func New[T Weight](n uint) Matrix[T] {
+	return Matrix[T]{n, make([]T, n*n)}
+}
+
+func NewD2(n uint) D2 {
+	return New[uint8](n)
+}
+
+func (d *Matrix[T]) At(i, j uint) T {
+	return d.Arr[i*d.RowSize+j]
+}
+
+func (d *Matrix[T]) Set(i, j uint, val T) {
+	d.Arr[i*d.RowSize+j] = val
+}
+
+func (d *Matrix[T]) String() string {
+	var s strings.Builder
+	var recycled []byte
+	for i := uint(0); i < uint(d.RowSize); i++ {
+		for j := uint(0); j < uint(d.RowSize); j++ {
+			recycled = strconv.AppendUint(recycled[:0], uint64(d.At(i, j)), 10)
+			s.Write(recycled)
+			s.WriteByte('\t')
+		}
+		s.WriteByte('\n')
+	}
+	return s.String()
+}
+
+// Sub returns the matrix restricted to indexes, in that order.
+func (d *Matrix[T]) Sub(indexes []uint) Matrix[T] {
+	sub := New[T](uint(len(indexes)))
+	for i, from := range indexes {
+		for j, to := range indexes {
+			sub.Set(uint(i), uint(j), d.At(from, to))
+		}
+	}
+	return sub
+}
+
+// Convert copies m into a matrix of an other element type.
+// Narrowing saturates, values that don't fit become [Max] which keeps unreachable pairs unreachable.
+func Convert[To, From Weight](m Matrix[From]) Matrix[To] {
+	to := New[To](m.RowSize)
+	inf := uint64(Max[To]())
+	fromInf := Max[From]()
+	for i, v := range m.Arr {
+		if v == fromInf || uint64(v) > inf {
+			to.Arr[i] = Max[To]()
+			continue
+		}
+		to.Arr[i] = To(v)
+	}
+	return to
+}
+
+// FloydWarshall relaxes m in place into the shortest path between every pair, [Max] is treated as infinity.
+// It is O(n³) and reports its progress to log every 2³² steps, nil keeps quiet.
+func FloydWarshall[T Weight](m Matrix[T], log *log.Logger) {
+	inf := uint64(Max[T]())
+	oneRow := m.RowSize
+	total := oneRow * oneRow * oneRow
+	var done uint
+	for k := range oneRow {
+		for i := range oneRow {
+			ik := uint64(m.At(i, k))
+			if ik >= inf {
+				done += oneRow
+				continue
+			}
+			for j := range oneRow {
+				done++
+				if log != nil && done%(1<<32) == 0 {
+					log.Printf("O(n³) full matrix solver: %d/%d %.2f%%", done, total, float64(done)/float64(total)*100)
+				}
+
+				new := ik + uint64(m.At(k, j))
+				if new >= inf {
+					continue
+				}
+				m.Set(i, j, min(m.At(i, j), T(new)))
+			}
+		}
+	}
+}
+
+// AllPairs computes the shortest jump count between every pair of nodes.
+// nodes maps matrix indexes to IDs and indexes is its inverse, edges between nodes missing from indexes are ignored.
+// Pairs that can't reach each other are left at [Infinity]. The progress goes to log, see [FloydWarshall].
+func AllPairs(nodes []uint32, indexes map[uint32]uint, edges map[uint32][]uint32, log *log.Logger) D2 {
+	distances := NewD2(uint(len(nodes)))
+	// Default to max
+	for i := range distances.Arr {
+		distances.Arr[i] = Infinity
+	}
+	// Setup the diagonal
+	for i := range uint(len(nodes)) {
+		distances.Set(i, i, 0)
+	}
+	// Setup the edges
+	for from, tos := range edges {
+		fromIndex, ok := indexes[from]
+		if !ok {
+			continue
+		}
+		for _, to := range tos {
+			toIndex, ok := indexes[to]
+			if !ok {
+				continue
+			}
+
+			distances.Set(fromIndex, toIndex, 1)
+		}
+	}
+	FloydWarshall(distances, log)
+	return distances
+}
+
+// Edge is a directed edge towards the matrix index To.
+type Edge[T Weight] struct {
+	To   uint
+	Cost T
+}
+
+// AllPairsSparse computes the cheapest path between every pair of nodes with one Dijkstra per node.
+// adjacency[i] are the edges leaving node i, this is much faster than [FloydWarshall] on the stargate graph.
+// Costs saturate just below [Max] which is left for unreachable pairs.
+func AllPairsSparse[T Weight](adjacency [][]Edge[T]) Matrix[T] {
+	n := uint(len(adjacency))
+	m := New[T](n)
+	for i := range m.Arr {
+		m.Arr[i] = Max[T]()
+	}
+
+	sources := make(chan uint)
+	var wg sync.WaitGroup
+	for range runtime.GOMAXPROCS(0) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dist := make([]uint64, n)
+			for source := range sources {
+				Dijkstra(adjacency, source, dist)
+				for j, d := range dist {
+					m.Set(source, uint(j), T(min(d, uint64(Max[T]()))))
+				}
+			}
+		}()
+	}
+	for i := range n {
+		sources <- i
+	}
+	close(sources)
+	wg.Wait()
+	return m
+}
+
+type queueItem struct {
+	node uint
+	dist uint64
+}
+
+type queue []queueItem
+
+func (q queue) Len() int           { return len(q) }
+func (q queue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q queue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x any)        { *q = append(*q, x.(queueItem)) }
+func (q *queue) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// Dijkstra fills dist with the cost from source, dist must be as long as adjacency.
+// Costs saturate just below [Max] of T, unreachable nodes get math.MaxUint64.
+func Dijkstra[T Weight](adjacency [][]Edge[T], source uint, dist []uint64) {
+	for i := range dist {
+		dist[i] = math.MaxUint64
+	}
+	dist[source] = 0
+	q := queue{{source, 0}}
+	for q.Len() > 0 {
+		item := heap.Pop(&q).(queueItem)
+		if item.dist > dist[item.node] {
+			continue
+		}
+		for _, e := range adjacency[item.node] {
+			d := item.dist + uint64(e.Cost)
+			if d < dist[e.To] {
+				dist[e.To] = d
+				heap.Push(&q, queueItem{e.To, d})
+			}
+		}
+	}
+	for i, d := range dist {
+		if d != math.MaxUint64 {
+			dist[i] = min(d, uint64(Max[T]())-1)
+		}
+	}
+}
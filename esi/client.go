@@ -0,0 +1,98 @@
+// Package esi is a small client for the parts of EVE's ESI API and SSO this project uses.
+package esi
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "https://esi.evetech.net"
+	DefaultSSOURL  = "https://login.eveonline.com"
+)
+
+// Client talks to ESI and the SSO, the URLs can be pointed at a mirror or at esitest's fake server.
+type Client struct {
+	HTTP    *http.Client
+	BaseURL string
+	SSOURL  string
+	// Limiter paces every request sent to ESI, shared between goroutines.
+	Limiter *Limiter
+	// OpenBrowser sends the user to the SSO login page, tests replace it with esitest.Server.Browse.
+	OpenBrowser func(url string) error
+}
+
+// NewClient returns a client for the live ESI.
+func NewClient() *Client {
+	rt := http.DefaultTransport.(*http.Transport).Clone()
+	rt.ForceAttemptHTTP2 = false
+	rt.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	rt.TLSNextProto = make(map[string]func(authority string, c *tls.Conn) http.RoundTripper)
+
+	return &Client{
+		HTTP: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: rt,
+		},
+		BaseURL: DefaultBaseURL,
+		SSOURL:  DefaultSSOURL,
+		Limiter: NewLimiter(),
+		OpenBrowser: func(url string) error {
+			return exec.Command("xdg-open", url).Run()
+		},
+	}
+}
+
+// Fetch GETs path relative to BaseURL and decodes the JSON response into v.
+func (c *Client) Fetch(path string, v any) error {
+	return c.FetchAuth(path, "", v)
+}
+
+// FetchAuth is [Client.Fetch] for authenticated endpoints, an empty token sends no Authorization header.
+func (c *Client) FetchAuth(path, token string, v any) error {
+	_, err := c.fetch(path, token, v)
+	return err
+}
+
+// fetch is [Client.FetchAuth] also returning the response headers, for paginated endpoints.
+func (c *Client) fetch(path, token string, v any) (http.Header, error) {
+	url := c.BaseURL + path
+	// Wait before starting the deadline, backing off for the error budget can take up to a minute.
+	err := c.Limiter.Wait(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("waiting for rate limiter: %w", err)
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	r, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", url, err)
+	}
+	defer r.Body.Close()
+	c.Limiter.Observe(r.Header)
+
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", url, r.Status)
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", url, err)
+	}
+
+	return r.Header, nil
+}
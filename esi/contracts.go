@@ -0,0 +1,48 @@
+package esi
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Contract is a public contract, trimmed to what courier planning needs.
+type Contract struct {
+	ContractID uint32 `json:"contract_id"`
+	// Type is "courier", "item_exchange", "auction" or "unknown".
+	Type string `json:"type"`
+	// StartLocationID and EndLocationID are stations or structures.
+	StartLocationID uint64    `json:"start_location_id"`
+	EndLocationID   uint64    `json:"end_location_id"`
+	Volume          float64   `json:"volume"`
+	Reward          float64   `json:"reward"`
+	Collateral      float64   `json:"collateral"`
+	DateExpired     time.Time `json:"date_expired"`
+	DaysToComplete  int       `json:"days_to_complete"`
+}
+
+// PublicContracts lists the outstanding public contracts of a region, following every page.
+func (c *Client) PublicContracts(region uint32) ([]Contract, error) {
+	return fetchPages[Contract](c, idPath("/v1/contracts/public/", region))
+}
+
+// fetchPages GETs every page of a paginated endpoint, path may already have a query.
+func fetchPages[T any](c *Client, path string) ([]T, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	var all []T
+	for page, pages := 1, 1; page <= pages; page++ {
+		var batch []T
+		header, err := c.fetch(path+sep+"page="+strconv.Itoa(page), "", &batch)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := strconv.Atoi(header.Get("X-Pages")); err == nil {
+			pages = n
+		}
+		all = append(all, batch...)
+	}
+	return all, nil
+}
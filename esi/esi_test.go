@@ -0,0 +1,91 @@
+package esi_test
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"eve-tour/esitest"
+)
+
+// newClient points a client at a fake ESI serving the fixture universe.
+func TestLogin(t *testing.T) {
+	c, _ := esitest.NewClient(t)
+	token, character, err := c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == "" {
+		t.Error("got an empty token")
+	}
+	if character != esitest.CharacterID {
+		t.Errorf("character = %d, want %d", character, esitest.CharacterID)
+	}
+}
+
+func TestTokenNeedsMatchingVerifier(t *testing.T) {
+	_, srv := esitest.NewClient(t)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(srv.URL + "/v2/oauth/authorize/?redirect_uri=" + url.QueryEscape("http://localhost/") +
+		"&code_challenge_method=S256&code_challenge=not-the-hash&state=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	redirect, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = http.PostForm(srv.URL+"/v2/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {redirect.Query().Get("code")},
+		"code_verifier": {"some verifier"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("token exchange with a wrong verifier: %s, want 400", resp.Status)
+	}
+}
+
+func TestAddWaypoints(t *testing.T) {
+	c, srv := esitest.NewClient(t)
+	token, _, err := c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := []uint32{esitest.Niyabainen, 60001456, esitest.Nourvukaiken}
+	err = c.AddWaypoints(token, route)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []esitest.Waypoint{
+		{DestinationID: esitest.Niyabainen, ClearOtherWaypoints: true},
+		{DestinationID: 60001456},
+		{DestinationID: esitest.Nourvukaiken},
+	}
+	if got := srv.Waypoints(); !slices.Equal(got, want) {
+		t.Errorf("recorded waypoints %+v, want %+v", got, want)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	c, srv := esitest.NewClient(t)
+	token, character, err := c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetLocation(esitest.Perimeter)
+	system, err := c.Location(token, character)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if system != esitest.Perimeter {
+		t.Errorf("location = %d, want Perimeter", system)
+	}
+}
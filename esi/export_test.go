@@ -0,0 +1,13 @@
+package esi
+
+import "time"
+
+// Expire makes the session's access token look old, as after a long break.
+func (s *Session) Expire() {
+	s.at = time.Now().Add(-TokenLifetime)
+}
+
+// RefreshToken is what the session refreshes its access token with.
+func (s *Session) RefreshToken() string {
+	return s.refresh
+}
@@ -0,0 +1,90 @@
+package esi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// errorBudgetFloor is how many errors we keep in reserve, when ESI's error budget drops below it we stop sending requests until the window resets.
+const errorBudgetFloor = 10
+
+// Limiter paces requests to ESI and backs off when the error budget is about to run out.
+// ESI bans IPs that burn through the whole error budget, so this is shared by every goroutine talking to it.
+type Limiter struct {
+	mu           sync.Mutex
+	interval     time.Duration // minimum delay between two requests, 0 means unlimited
+	next         time.Time
+	errorsRemain int
+	errorsReset  time.Time
+}
+
+// NewLimiter returns an unlimited limiter assuming a full error budget.
+func NewLimiter() *Limiter {
+	return &Limiter{errorsRemain: 100}
+}
+
+// SetRate caps the number of requests per second, 0 disables the cap.
+func (l *Limiter) SetRate(perSecond float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if perSecond <= 0 {
+		l.interval = 0
+		return
+	}
+	l.interval = time.Duration(float64(time.Second) / perSecond)
+}
+
+// Wait blocks until the caller is allowed to send a request.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := now
+	if l.next.After(at) {
+		at = l.next
+	}
+	if l.errorsRemain < errorBudgetFloor && l.errorsReset.After(at) {
+		at = l.errorsReset
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Observe updates the error budget from the headers of an ESI response.
+func (l *Limiter) Observe(h http.Header) {
+	remain, err := strconv.Atoi(h.Get("X-ESI-Error-Limit-Remain"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.Atoi(h.Get("X-ESI-Error-Limit-Reset"))
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errorsRemain = remain
+	l.errorsReset = time.Now().Add(time.Duration(reset) * time.Second)
+}
+
+// Budget returns the last error budget reported by ESI.
+func (l *Limiter) Budget() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.errorsRemain
+}
@@ -1,4 +1,4 @@
-package main
+package esi
 
 import (
 	crand "crypto/rand"
@@ -10,10 +10,8 @@ import (
 	"net"
 	"net/http"
 	"net/url"
-	"os/exec"
 	"strconv"
 	"strings"
-	"time"
 )
 
 const appId = "bac8e360dacc4dad85a1cc7173e78cb3"
@@ -21,18 +19,36 @@ const appId = "bac8e360dacc4dad85a1cc7173e78cb3"
 var neededPerms = []string{
 	"esi-ui.write_waypoint.v1",
 	"esi-location.read_location.v1",
+	"esi-universe.read_structures.v1",
 }
 
 var exitPage = []byte(`<!doctypehtml><title>EVE-LKH</title><style>body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",Roboto,Helvetica,Arial,sans-serif;display:flex;justify-content:center;align-items:center;height:100vh;margin:0;font-size:20px;color:#4a4a4a;background-color:#fafafa;line-height:1.6;letter-spacing:.2px}p{padding:20px;max-width:600px;text-align:center}</style><p>You can close this tab now.</p><script async>window.close()</script>`)
 
 type ssoResponseJson struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
-func grabUserToken() (authToken string, characterId uint32, err error) {
+// Login runs the SSO PKCE flow through the user's browser and returns an access token and the character that logged in.
+// Use a [Session] to keep it fresh without going through the browser again.
+func (c *Client) Login() (authToken string, characterId uint32, err error) {
+	tokens, err := c.login()
+	if err != nil {
+		return "", 0, err
+	}
+	return tokens.AccessToken, tokens.character, nil
+}
+
+// tokens are what the SSO handed out, along with the character the access token belongs to.
+type tokens struct {
+	ssoResponseJson
+	character uint32
+}
+
+func (c *Client) login() (tokens, error) {
 	listener, err := net.Listen("tcp", "localhost:13377")
 	if err != nil {
-		return "", 0, fmt.Errorf("listening: %w", err)
+		return tokens{}, fmt.Errorf("listening: %w", err)
 	}
 	defer listener.Close()
 
@@ -40,7 +56,7 @@ func grabUserToken() (authToken string, characterId uint32, err error) {
 	var rng [len(state) + len(codeVerifier)]byte
 	_, err = io.ReadFull(crand.Reader, rng[:])
 	if err != nil {
-		return "", 0, fmt.Errorf("getting randomness: %w", err)
+		return tokens{}, fmt.Errorf("getting randomness: %w", err)
 	}
 
 	copy(state[:], rng[:len(state)])
@@ -51,8 +67,10 @@ func grabUserToken() (authToken string, characterId uint32, err error) {
 	challengeStr := base64.RawURLEncoding.EncodeToString(challenge[:])
 
 	exitServer := make(chan struct{})
-	token := make(chan string)
+	token := make(chan ssoResponseJson)
 	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a kept alive connection would still reach this handler on the next login, which listens on the same port
+		w.Header().Set("Connection", "close")
 		state := r.URL.Query().Get("state")
 		if state != stateStr {
 			http.Error(w, "invalid state", http.StatusBadRequest)
@@ -60,7 +78,7 @@ func grabUserToken() (authToken string, characterId uint32, err error) {
 		}
 
 		code := r.URL.Query().Get("code")
-		resp, err := client.PostForm("https://login.eveonline.com/v2/oauth/token", url.Values{
+		resp, err := c.HTTP.PostForm(c.SSOURL+"/v2/oauth/token", url.Values{
 			"grant_type":    {"authorization_code"},
 			"client_id":     {appId},
 			"code":          {code},
@@ -87,7 +105,7 @@ func grabUserToken() (authToken string, characterId uint32, err error) {
 
 		select {
 		case <-exitServer:
-		case token <- ssoResp.AccessToken:
+		case token <- ssoResp:
 		}
 
 		w.Header().Set("Content-Type", "text/html")
@@ -96,7 +114,7 @@ func grabUserToken() (authToken string, characterId uint32, err error) {
 	}))
 
 	// get user token
-	userUrl := "https://login.eveonline.com/v2/oauth/authorize/?" +
+	userUrl := c.SSOURL + "/v2/oauth/authorize/?" +
 		"response_type=code&" +
 		"redirect_uri=" + url.QueryEscape("http://localhost:13377/") +
 		"&client_id=" + appId +
@@ -105,118 +123,72 @@ func grabUserToken() (authToken string, characterId uint32, err error) {
 		"&code_challenge=" + challengeStr +
 		"&state=" + stateStr
 
-	err = exec.Command("xdg-open", userUrl).Run()
+	err = c.OpenBrowser(userUrl)
 	if err != nil {
-		return "", 0, fmt.Errorf("opening browser: %w", err)
+		return tokens{}, fmt.Errorf("opening browser: %w", err)
 	}
 
-	authToken = <-token
+	resp := <-token
 	close(exitServer)
+	return withCharacter(resp)
+}
 
-	jwtSections := strings.Split(authToken, ".")
-	if len(jwtSections) != 3 {
-		return "", 0, fmt.Errorf("invalid JWT")
-	}
-	payload, err := base64.RawStdEncoding.DecodeString(jwtSections[1])
+// refresh trades a refresh token for new tokens, the SSO may hand out a new refresh token too.
+func (c *Client) refresh(refreshToken string) (tokens, error) {
+	resp, err := c.HTTP.PostForm(c.SSOURL+"/v2/oauth/token", url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {appId},
+		"refresh_token": {refreshToken},
+	})
 	if err != nil {
-		return "", 0, fmt.Errorf("decoding JWT payload: %w", err)
+		return tokens{}, fmt.Errorf("refreshing token: %w", err)
 	}
+	defer resp.Body.Close()
 
-	var jwt jwtJson
-	err = json.Unmarshal(payload, &jwt)
-	if err != nil {
-		return "", 0, fmt.Errorf("decoding JWT json: %w", err)
-	}
-	id := strings.TrimPrefix(jwt.Sub, "CHARACTER:EVE:")
-	if id == jwt.Sub {
-		return "", 0, fmt.Errorf("invalid JWT sub")
+	if resp.StatusCode != http.StatusOK {
+		return tokens{}, fmt.Errorf("refreshing token: %s", resp.Status)
 	}
 
-	characterId64, err := strconv.ParseUint(id, 10, 32)
+	var ssoResp ssoResponseJson
+	err = json.NewDecoder(resp.Body).Decode(&ssoResp)
 	if err != nil {
-		return "", 0, fmt.Errorf("parsing character ID: %w", err)
+		return tokens{}, fmt.Errorf("decoding token: %w", err)
 	}
-
-	return authToken, uint32(characterId64), nil
-}
-
-type locationJson struct {
-	SolarSystemID uint32 `json:"solar_system_id"`
+	if ssoResp.RefreshToken == "" {
+		ssoResp.RefreshToken = refreshToken
+	}
+	return withCharacter(ssoResp)
 }
 
-func getLocation(auth string, characterId uint32) (uint32, error) {
-	req, err := http.NewRequest(http.MethodGet, baseUrl+"/v2/characters/"+strconv.FormatUint(uint64(characterId), 10)+"/location/", nil)
+// withCharacter reads the character out of the access token's JWT.
+func withCharacter(resp ssoResponseJson) (tokens, error) {
+	jwtSections := strings.Split(resp.AccessToken, ".")
+	if len(jwtSections) != 3 {
+		return tokens{}, fmt.Errorf("invalid JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(jwtSections[1]) // JWT sections are base64url
 	if err != nil {
-		return 0, fmt.Errorf("creating request: %w", err)
+		return tokens{}, fmt.Errorf("decoding JWT payload: %w", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+auth)
 
-	resp, err := client.Do(req)
+	var jwt jwtJson
+	err = json.Unmarshal(payload, &jwt)
 	if err != nil {
-		return 0, fmt.Errorf("getting location: %w", err)
+		return tokens{}, fmt.Errorf("decoding JWT json: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("getting location: %s", resp.Status)
+	id := strings.TrimPrefix(jwt.Sub, "CHARACTER:EVE:")
+	if id == jwt.Sub {
+		return tokens{}, fmt.Errorf("invalid JWT sub")
 	}
 
-	var loc locationJson
-	err = json.NewDecoder(resp.Body).Decode(&loc)
+	characterId64, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		return 0, fmt.Errorf("decoding location: %w", err)
+		return tokens{}, fmt.Errorf("parsing character ID: %w", err)
 	}
 
-	return loc.SolarSystemID, nil
+	return tokens{ssoResponseJson: resp, character: uint32(characterId64)}, nil
 }
 
 type jwtJson struct {
 	Sub string `json:"sub"`
 }
-
-func addWaypoints(g graph, token string, route []uint32) error {
-	const minBackoff = time.Second
-	backoff := minBackoff
-	for i, system := range route {
-		err := addWaypoint(token, system, i == 0)
-		if err != nil {
-			fmt.Println("failed to add waypoint to", system, ":", err)
-			backoff *= 2
-		} else {
-			backoff = max(backoff/2, minBackoff)
-		}
-		time.Sleep(backoff)
-	}
-
-	return nil
-}
-
-func addWaypoint(auth string, system uint32, overwriteRoute bool) error {
-	overwrite := "false"
-	if overwriteRoute {
-		// overwrite the existing route at the beginning
-		overwrite = "true"
-	}
-
-	url := baseUrl + "/v2/ui/autopilot/waypoint/" +
-		"?add_to_beginning=false" +
-		"&clear_other_waypoints=" + overwrite +
-		"&destination_id=" + strconv.FormatUint(uint64(system), 10)
-	req, err := http.NewRequest(http.MethodPost, url, nil)
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+auth)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("adding waypoint: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("adding waypoint: %s", resp.Status)
-	}
-
-	return nil
-}
@@ -0,0 +1,37 @@
+package esi
+
+import "time"
+
+// MarketOrder is an order of a region's market.
+type MarketOrder struct {
+	OrderID    uint64  `json:"order_id"`
+	TypeID     uint32  `json:"type_id"`
+	LocationID uint64  `json:"location_id"`
+	SystemID   uint32  `json:"system_id"`
+	IsBuyOrder bool    `json:"is_buy_order"`
+	Price      float64 `json:"price"`
+	// VolumeRemain is a unit count, MinVolume the least a buy order accepts at once.
+	VolumeRemain int64     `json:"volume_remain"`
+	MinVolume    int64     `json:"min_volume"`
+	Range        string    `json:"range"`
+	Issued       time.Time `json:"issued"`
+	Duration     int       `json:"duration"`
+}
+
+// Type is an item type, volumes are in m³.
+type Type struct {
+	Name           string  `json:"name"`
+	Volume         float64 `json:"volume"`
+	PackagedVolume float64 `json:"packaged_volume"`
+}
+
+// MarketOrders lists every buy and sell order of a region, following every page.
+func (c *Client) MarketOrders(region uint32) ([]MarketOrder, error) {
+	return fetchPages[MarketOrder](c, idPath("/v1/markets/", region)+"orders/?order_type=all")
+}
+
+func (c *Client) Type(id uint32) (Type, error) {
+	var t Type
+	err := c.Fetch(idPath("/v3/universe/types/", id), &t)
+	return t, err
+}
@@ -0,0 +1,45 @@
+package esi
+
+import (
+	"fmt"
+	"time"
+)
+
+// TokenLifetime is a bit under the 20 minutes SSO access tokens last.
+const TokenLifetime = 15 * time.Minute
+
+// Session keeps the access token of a long running command fresh.
+// It logs in through the browser the first time, then uses the refresh token, only going back to the browser if the SSO refuses it.
+type Session struct {
+	Client *Client
+
+	token     string
+	refresh   string
+	character uint32
+	at        time.Time
+}
+
+// Token returns a valid access token and the character it belongs to, refreshing or logging in if needed.
+func (s *Session) Token() (string, uint32, error) {
+	if s.token != "" && time.Since(s.at) < TokenLifetime {
+		return s.token, s.character, nil
+	}
+	if s.refresh != "" {
+		t, err := s.Client.refresh(s.refresh)
+		if err == nil {
+			s.use(t)
+			return s.token, s.character, nil
+		}
+		// revoked or expired after a long break, the browser it is
+	}
+	t, err := s.Client.login()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to grab user token: %w", err)
+	}
+	s.use(t)
+	return s.token, s.character, nil
+}
+
+func (s *Session) use(t tokens) {
+	s.token, s.refresh, s.character, s.at = t.AccessToken, t.RefreshToken, t.character, time.Now()
+}
@@ -0,0 +1,48 @@
+package esi_test
+
+import (
+	"testing"
+
+	"eve-tour/esi"
+	"eve-tour/esitest"
+)
+
+func TestSessionRefreshes(t *testing.T) {
+	c, srv := esitest.NewClient(t)
+	var logins int
+	c.OpenBrowser = func(url string) error {
+		logins++
+		return srv.Browse(url)
+	}
+	s := &esi.Session{Client: c}
+
+	token, character, err := s.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == "" || character != esitest.CharacterID || logins != 1 {
+		t.Fatalf("first token %q for %d after %d logins", token, character, logins)
+	}
+
+	s.Expire()
+	for range 2 {
+		refresh := s.RefreshToken()
+		_, character, err = s.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if character != esitest.CharacterID || logins != 1 || s.RefreshToken() == refresh {
+			t.Errorf("refreshed for %d after %d logins, refresh token %q then %q", character, logins, refresh, s.RefreshToken())
+		}
+		s.Expire()
+	}
+
+	srv.RevokeRefreshTokens()
+	_, _, err = s.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logins != 2 {
+		t.Errorf("%d logins, want the browser again once the refresh token is refused", logins)
+	}
+}
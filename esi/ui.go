@@ -0,0 +1,97 @@
+package esi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type locationJson struct {
+	SolarSystemID uint32 `json:"solar_system_id"`
+}
+
+// Location returns the solar system the character is currently in.
+func (c *Client) Location(auth string, characterId uint32) (uint32, error) {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/v2/characters/"+strconv.FormatUint(uint64(characterId), 10)+"/location/", nil)
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+auth)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("getting location: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("getting location: %s", resp.Status)
+	}
+
+	var loc locationJson
+	err = json.NewDecoder(resp.Body).Decode(&loc)
+	if err != nil {
+		return 0, fmt.Errorf("decoding location: %w", err)
+	}
+
+	return loc.SolarSystemID, nil
+}
+
+// AddWaypoints replaces the in-game autopilot route with route, slowing down and retrying when ESI starts failing.
+// It stops at the first waypoint that still fails, the in-game route then ends before it.
+func (c *Client) AddWaypoints(token string, route []uint32) error {
+	const minBackoff = time.Second
+	const attempts = 3
+	backoff := minBackoff
+	for i, system := range route {
+		var err error
+		for range attempts {
+			err = c.AddWaypoint(token, system, i == 0)
+			if err == nil {
+				break
+			}
+			backoff *= 2
+			time.Sleep(backoff)
+		}
+		if err != nil {
+			return fmt.Errorf("adding waypoint %d of %d: %w", i+1, len(route), err)
+		}
+		backoff = max(backoff/2, minBackoff)
+		time.Sleep(backoff)
+	}
+
+	return nil
+}
+
+// AddWaypoint appends system to the autopilot route, or replaces the route with it if overwriteRoute is set.
+func (c *Client) AddWaypoint(auth string, system uint32, overwriteRoute bool) error {
+	overwrite := "false"
+	if overwriteRoute {
+		// overwrite the existing route at the beginning
+		overwrite = "true"
+	}
+
+	url := c.BaseURL + "/v2/ui/autopilot/waypoint/" +
+		"?add_to_beginning=false" +
+		"&clear_other_waypoints=" + overwrite +
+		"&destination_id=" + strconv.FormatUint(uint64(system), 10)
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+auth)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("adding waypoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("adding waypoint: %s", resp.Status)
+	}
+
+	return nil
+}
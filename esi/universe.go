@@ -0,0 +1,121 @@
+package esi
+
+import (
+	"net/url"
+	"strconv"
+)
+
+type System struct {
+	Name            string   `json:"name"`
+	Stargates       []uint32 `json:"stargates"`
+	ConstellationID uint32   `json:"constellation_id"`
+	Stations        []uint32 `json:"stations"`
+	SecurityStatus  float32  `json:"security_status"`
+}
+
+type Constellation struct {
+	Name     string `json:"name"`
+	RegionID uint32 `json:"region_id"`
+}
+
+type Region struct {
+	Name string `json:"name"`
+}
+
+// Position is in meters, relative to the system's star.
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+type Stargate struct {
+	SystemID    uint32   `json:"system_id"`
+	Position    Position `json:"position"`
+	Destination struct {
+		StargateID uint32 `json:"stargate_id"`
+		SystemID   uint32 `json:"system_id"`
+	} `json:"destination"`
+}
+
+type Station struct {
+	Name                  string   `json:"name"`
+	SystemID              uint32   `json:"system_id"`
+	TypeID                uint32   `json:"type_id"`
+	Owner                 uint32   `json:"owner"`
+	Services              []string `json:"services"`
+	MaxDockableShipVolume float64  `json:"max_dockable_ship_volume"`
+}
+
+// Structure is a player owned Upwell structure, ESI only tells its name and owner to characters allowed to dock.
+type Structure struct {
+	Name          string `json:"name"`
+	OwnerID       uint32 `json:"owner_id"`
+	SolarSystemID uint32 `json:"solar_system_id"`
+	TypeID        uint32 `json:"type_id"`
+}
+
+func idPath(prefix string, id uint32) string {
+	return prefix + strconv.FormatUint(uint64(id), 10) + "/"
+}
+
+// Systems lists the IDs of every solar system, including wormholes and other unreachable ones.
+func (c *Client) Systems() ([]uint32, error) {
+	var systems []uint32
+	err := c.Fetch("/v1/universe/systems/", &systems)
+	return systems, err
+}
+
+func (c *Client) System(id uint32) (System, error) {
+	var s System
+	err := c.Fetch(idPath("/v4/universe/systems/", id), &s)
+	return s, err
+}
+
+func (c *Client) Constellation(id uint32) (Constellation, error) {
+	var cn Constellation
+	err := c.Fetch(idPath("/v1/universe/constellations/", id), &cn)
+	return cn, err
+}
+
+func (c *Client) Regions() ([]uint32, error) {
+	var regions []uint32
+	err := c.Fetch("/v1/universe/regions/", &regions)
+	return regions, err
+}
+
+func (c *Client) Region(id uint32) (Region, error) {
+	var r Region
+	err := c.Fetch(idPath("/v1/universe/regions/", id), &r)
+	return r, err
+}
+
+func (c *Client) Stargate(id uint32) (Stargate, error) {
+	var sg Stargate
+	err := c.Fetch(idPath("/v1/universe/stargates/", id), &sg)
+	return sg, err
+}
+
+func (c *Client) Station(id uint32) (Station, error) {
+	var st Station
+	err := c.Fetch(idPath("/v2/universe/stations/", id), &st)
+	return st, err
+}
+
+// Structures lists the public structures, filter is "" for all of them, "market" or "manufacturing_basic".
+func (c *Client) Structures(filter string) ([]uint64, error) {
+	path := "/v1/universe/structures/"
+	if filter != "" {
+		path += "?filter=" + url.QueryEscape(filter)
+	}
+	var ids []uint64
+	err := c.Fetch(path, &ids)
+	return ids, err
+}
+
+// Structure needs a token with the esi-universe.read_structures.v1 scope.
+func (c *Client) Structure(token string, id uint64) (Structure, error) {
+	var st Structure
+	err := c.FetchAuth("/v2/universe/structures/"+strconv.FormatUint(id, 10)+"/", token, &st)
+	return st, err
+}
@@ -0,0 +1,29 @@
+package esitest
+
+import (
+	"testing"
+
+	"eve-tour/esi"
+	"eve-tour/universe"
+)
+
+// NewClient starts a server for the fixture universe, closed with the test, and returns a client of it that logs in without a browser.
+func NewClient(t testing.TB) (*esi.Client, *Server) {
+	t.Helper()
+	srv := NewServer(Fixture())
+	t.Cleanup(srv.Close)
+	c := esi.NewClient()
+	c.BaseURL, c.SSOURL, c.OpenBrowser = srv.URL, srv.URL, srv.Browse
+	return c, srv
+}
+
+// Graph crawls the fixture universe and builds it, as a first run does.
+func Graph(t testing.TB, opts universe.CrawlOptions) universe.Graph {
+	t.Helper()
+	c, _ := NewClient(t)
+	g, err := universe.Crawl(c, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return universe.Build(g, opts.OnlyHighsec, nil)
+}
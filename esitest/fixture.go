@@ -0,0 +1,214 @@
+package esitest
+
+import "time"
+
+type Region struct {
+	Name string
+}
+
+type Constellation struct {
+	Name     string
+	RegionID uint32
+}
+
+type System struct {
+	Name            string
+	ConstellationID uint32
+	SecurityStatus  float32
+	Stations        []uint32
+	Stargates       []uint32
+}
+
+type Stargate struct {
+	SystemID        uint32
+	Destination     uint32
+	DestinationGate uint32
+	// Position is in meters.
+	Position [3]float64
+}
+
+type Station struct {
+	Name                  string
+	SystemID              uint32
+	TypeID                uint32
+	Owner                 uint32
+	Services              []string
+	MaxDockableShipVolume float64
+}
+
+type Structure struct {
+	Name     string
+	SystemID uint32
+	TypeID   uint32
+	Owner    uint32
+	// Market and Manufacturing put the structure in the filtered public lists.
+	Market, Manufacturing bool
+}
+
+// Contract is a public contract of Region, From and To are station or structure IDs.
+type Contract struct {
+	Region                     uint32
+	Type                       string
+	From, To                   uint64
+	Volume, Reward, Collateral float64
+	Expires                    time.Time
+	DaysToComplete             int
+}
+
+// Order is a market order of Region, Location is a station or structure ID.
+type Order struct {
+	Region    uint32
+	Type      uint32
+	Location  uint64
+	Buy       bool
+	Price     float64
+	Remain    int64
+	MinVolume int64
+}
+
+// Type is an item type, volumes are in m³.
+type Type struct {
+	Name                   string
+	Volume, PackagedVolume float64
+}
+
+// Universe is the static data served by the fake ESI.
+type Universe struct {
+	Regions        map[uint32]Region
+	Constellations map[uint32]Constellation
+	Systems        map[uint32]System
+	Stargates      map[uint32]Stargate
+	Stations       map[uint32]Station
+	Structures     map[uint64]Structure
+	Contracts      map[uint32]Contract
+	Orders         map[uint64]Order
+	Types          map[uint32]Type
+}
+
+const au = 149597870700
+
+// gatePosition spreads gates a few AU apart, deterministically so travel times are stable.
+func gatePosition(gate uint32) [3]float64 {
+	return [3]float64{
+		float64(gate%7) * 4 * au,
+		float64(gate%5) * 3 * au,
+		float64(gate%3) * au,
+	}
+}
+
+// Link adds a pair of stargates connecting a and b.
+func (u *Universe) Link(a, b uint32) {
+	gateA := uint32(50000000 + len(u.Stargates))
+	gateB := gateA + 1
+	u.Stargates[gateA] = Stargate{SystemID: a, Destination: b, DestinationGate: gateB, Position: gatePosition(gateA)}
+	u.Stargates[gateB] = Stargate{SystemID: b, Destination: a, DestinationGate: gateA, Position: gatePosition(gateB)}
+
+	sa := u.Systems[a]
+	sa.Stargates = append(sa.Stargates, gateA)
+	u.Systems[a] = sa
+	sb := u.Systems[b]
+	sb.Stargates = append(sb.Stargates, gateB)
+	u.Systems[b] = sb
+}
+
+const (
+	TheForge  = 10000002
+	Lonetrek  = 10000016
+	Citadel   = 10000033
+	Kimotoro  = 20000020
+	Onirvura  = 20000019
+	Ihilakken = 20000132
+	Kainokai  = 20000387
+
+	Jita         = 30000142
+	Perimeter    = 30000144
+	NewCaldari   = 30000145
+	Niyabainen   = 30000143
+	Urlen        = 30000139
+	Sobaseki     = 30001363
+	Tunttaras    = 30002811
+	Nourvukaiken = 30002812
+	Uedama       = 30002768 // lowsec in the fixture so highsec-only crawls have something to drop
+)
+
+// Fixture returns a small universe loosely modelled on the area around Jita.
+// It has three regions, a lowsec system and a few stations, enough to drive the whole pipeline.
+func Fixture() Universe {
+	expires := time.Now().Add(7 * 24 * time.Hour).UTC().Truncate(time.Second)
+	u := Universe{
+		Regions: map[uint32]Region{
+			TheForge: {Name: "The Forge"},
+			Lonetrek: {Name: "Lonetrek"},
+			Citadel:  {Name: "The Citadel"},
+		},
+		Constellations: map[uint32]Constellation{
+			Kimotoro:  {Name: "Kimotoro", RegionID: TheForge},
+			Onirvura:  {Name: "Onirvura", RegionID: TheForge},
+			Ihilakken: {Name: "Ihilakken", RegionID: Lonetrek},
+			Kainokai:  {Name: "Kainokai", RegionID: Citadel},
+		},
+		Systems: map[uint32]System{
+			Jita:         {Name: "Jita", ConstellationID: Kimotoro, SecurityStatus: 0.95, Stations: []uint32{60003760, 60003466}},
+			Perimeter:    {Name: "Perimeter", ConstellationID: Kimotoro, SecurityStatus: 0.95, Stations: []uint32{60000361}},
+			NewCaldari:   {Name: "New Caldari", ConstellationID: Kimotoro, SecurityStatus: 1.0},
+			Niyabainen:   {Name: "Niyabainen", ConstellationID: Kimotoro, SecurityStatus: 0.96},
+			Urlen:        {Name: "Urlen", ConstellationID: Onirvura, SecurityStatus: 0.96},
+			Sobaseki:     {Name: "Sobaseki", ConstellationID: Kainokai, SecurityStatus: 0.77},
+			Tunttaras:    {Name: "Tunttaras", ConstellationID: Ihilakken, SecurityStatus: 0.85, Stations: []uint32{60001456}},
+			Nourvukaiken: {Name: "Nourvukaiken", ConstellationID: Ihilakken, SecurityStatus: 0.76},
+			Uedama:       {Name: "Uedama", ConstellationID: Kainokai, SecurityStatus: 0.4},
+		},
+		Stargates: make(map[uint32]Stargate),
+		Stations: map[uint32]Station{
+			60003760: {Name: "Jita IV - Moon 4 - Caldari Navy Assembly Plant", SystemID: Jita, TypeID: 1529, Owner: 1000035, MaxDockableShipVolume: 50000000,
+				Services: []string{"market", "cloning", "jump-clone-facility", "reprocessing-plant", "repair-facilities", "factory", "loyalty-point-store", "docking"}},
+			60003466: {Name: "Jita IV - Moon 4 - Caldari Business Tribunal", SystemID: Jita, TypeID: 1529, Owner: 1000033, MaxDockableShipVolume: 50000000,
+				Services: []string{"market", "docking", "office-rental"}},
+			60000361: {Name: "Perimeter II - Moon 1 - Hyasyoda Corporation Refinery", SystemID: Perimeter, TypeID: 1529, Owner: 1000004, MaxDockableShipVolume: 50000000,
+				Services: []string{"reprocessing-plant", "refinery", "docking"}},
+			60001456: {Name: "Tunttaras II - Moon 1 - Caldari Steel Factory", SystemID: Tunttaras, TypeID: 1529, Owner: 1000010, MaxDockableShipVolume: 50000000,
+				Services: []string{"factory", "cloning", "docking"}},
+		},
+		Structures: map[uint64]Structure{
+			1035466617946: {Name: "Perimeter - Tranquility Trading Tower", SystemID: Perimeter, TypeID: 35834, Owner: 98000001, Market: true, Manufacturing: true},
+			1036927076065: {Name: "Niyabainen - Quiet Harbor", SystemID: Niyabainen, TypeID: 35832, Owner: 98000002},
+		},
+		Contracts: map[uint32]Contract{
+			// Couriers out of Jita, plus one too big for anything but a freighter and one that isn't a courier.
+			200000001: {Region: TheForge, Type: "courier", From: 60003760, To: 60001456, Volume: 12000, Reward: 9000000, Collateral: 150000000, Expires: expires, DaysToComplete: 3},
+			200000002: {Region: TheForge, Type: "courier", From: 60003466, To: 60000361, Volume: 3000, Reward: 1500000, Collateral: 20000000, Expires: expires, DaysToComplete: 1},
+			200000003: {Region: TheForge, Type: "courier", From: 1035466617946, To: 60003760, Volume: 8000, Reward: 4000000, Collateral: 90000000, Expires: expires, DaysToComplete: 3},
+			200000004: {Region: TheForge, Type: "courier", From: 60003760, To: 60001456, Volume: 800000, Reward: 60000000, Collateral: 2000000000, Expires: expires, DaysToComplete: 7},
+			200000005: {Region: TheForge, Type: "item_exchange", From: 60003760, To: 60003760, Expires: expires},
+			200000006: {Region: Lonetrek, Type: "courier", From: 60001456, To: 1036927076065, Volume: 5000, Reward: 2500000, Collateral: 40000000, Expires: expires, DaysToComplete: 3},
+		},
+		Orders: map[uint64]Order{
+			// Tritanium is cheaper in Jita than what Tunttaras buys it for, Pyerite only pays in Tunttaras, Perimeter's Rifter loses money.
+			6000000001: {Region: TheForge, Type: 34, Location: 60003760, Price: 4, Remain: 1000000, MinVolume: 1},
+			6000000002: {Region: TheForge, Type: 34, Location: 60003760, Price: 4.2, Remain: 2000000, MinVolume: 1},
+			6000000003: {Region: Lonetrek, Type: 34, Location: 60001456, Buy: true, Price: 5.5, Remain: 1500000, MinVolume: 1},
+			6000000004: {Region: TheForge, Type: 35, Location: 60000361, Price: 10, Remain: 100000, MinVolume: 1},
+			6000000005: {Region: TheForge, Type: 35, Location: 60003760, Buy: true, Price: 9.5, Remain: 100000, MinVolume: 1},
+			6000000006: {Region: Lonetrek, Type: 35, Location: 60001456, Buy: true, Price: 12, Remain: 50000, MinVolume: 1},
+			6000000007: {Region: TheForge, Type: 587, Location: 60000361, Price: 500000, Remain: 10, MinVolume: 1},
+			6000000008: {Region: TheForge, Type: 587, Location: 60003760, Buy: true, Price: 480000, Remain: 10, MinVolume: 1},
+		},
+		Types: map[uint32]Type{
+			34:  {Name: "Tritanium", Volume: 0.01, PackagedVolume: 0.01},
+			35:  {Name: "Pyerite", Volume: 0.01, PackagedVolume: 0.01},
+			587: {Name: "Rifter", Volume: 27289, PackagedVolume: 2500},
+		},
+	}
+
+	u.Link(Jita, Perimeter)
+	u.Link(Jita, NewCaldari)
+	u.Link(Jita, Niyabainen)
+	u.Link(Perimeter, Urlen)
+	u.Link(NewCaldari, Niyabainen)
+	u.Link(Niyabainen, Tunttaras)
+	u.Link(Tunttaras, Nourvukaiken)
+	u.Link(Perimeter, Sobaseki)
+	u.Link(Sobaseki, Uedama)
+
+	return u
+}
@@ -0,0 +1,553 @@
+// Package esitest provides a fake ESI and SSO server for offline tests, in the spirit of net/http/httptest.
+// Point an [esi.Client]'s BaseURL and SSOURL at Server.URL and set its OpenBrowser to [Server.Browse], or let [NewClient] do it.
+package esitest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// CharacterID is the character every SSO login resolves to.
+const CharacterID = 90000001
+
+// Waypoint is one recorded POST to /v2/ui/autopilot/waypoint/.
+type Waypoint struct {
+	DestinationID       uint32
+	AddToBeginning      bool
+	ClearOtherWaypoints bool
+}
+
+type Server struct {
+	*httptest.Server
+	Universe Universe
+
+	mu        sync.Mutex
+	location  uint32
+	waypoints []Waypoint
+	// codes maps the authorization codes handed out to their PKCE challenge.
+	codes    map[string]string
+	nextCode int
+	// refreshTokens are the ones still valid, each is good for one refresh like with the real SSO.
+	refreshTokens map[string]struct{}
+}
+
+// NewServer starts a fake ESI and SSO serving u, both live on the same URL.
+// The character starts in Jita.
+func NewServer(u Universe) *Server {
+	s := &Server{
+		Universe:      u,
+		location:      Jita,
+		codes:         make(map[string]string),
+		refreshTokens: make(map[string]struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/universe/systems/", s.systems)
+	mux.HandleFunc("GET /v4/universe/systems/{id}/", s.system)
+	mux.HandleFunc("GET /v1/universe/constellations/{id}/", s.constellation)
+	mux.HandleFunc("GET /v1/universe/regions/", s.regions)
+	mux.HandleFunc("GET /v1/universe/regions/{id}/", s.region)
+	mux.HandleFunc("GET /v1/universe/stargates/{id}/", s.stargate)
+	mux.HandleFunc("GET /v2/universe/stations/{id}/", s.station)
+	mux.HandleFunc("GET /v1/universe/structures/", s.structures)
+	mux.HandleFunc("GET /v2/universe/structures/{id}/", s.structure)
+	mux.HandleFunc("GET /v1/contracts/public/{id}/", s.publicContracts)
+	mux.HandleFunc("GET /v1/markets/{id}/orders/", s.marketOrders)
+	mux.HandleFunc("GET /v3/universe/types/{id}/", s.itemType)
+	mux.HandleFunc("GET /v2/characters/{id}/location/", s.characterLocation)
+	mux.HandleFunc("POST /v2/ui/autopilot/waypoint/", s.waypoint)
+	mux.HandleFunc("GET /v2/oauth/authorize/", s.authorize)
+	mux.HandleFunc("POST /v2/oauth/token", s.token)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Waypoints returns every waypoint POSTed so far, in order.
+func (s *Server) Waypoints() []Waypoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.waypoints)
+}
+
+// SetLocation moves the character.
+func (s *Server) SetLocation(system uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.location = system
+}
+
+// RevokeRefreshTokens makes the refresh tokens handed out so far invalid, as if the user revoked the application.
+func (s *Server) RevokeRefreshTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.refreshTokens)
+}
+
+// Browse plays the part of the user's browser during the SSO flow: it follows the authorize URL and the redirect back to the local callback.
+// Like xdg-open it returns immediately, the callback only answers once the client picked up the token.
+func (s *Server) Browse(authorizeUrl string) error {
+	go func() {
+		resp, err := http.Get(authorizeUrl)
+		if err != nil {
+			return
+		}
+		resp.Body.Close()
+	}()
+	return nil
+}
+
+func writeJson(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-ESI-Error-Limit-Remain", "100")
+	w.Header().Set("X-ESI-Error-Limit-Reset", "60")
+	json.NewEncoder(w).Encode(v)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (uint32, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return 0, false
+	}
+	return uint32(id), true
+}
+
+func (s *Server) checkAuth(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Authorization") != "Bearer "+s.accessToken() {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (s *Server) systems(w http.ResponseWriter, r *http.Request) {
+	ids := make([]uint32, 0, len(s.Universe.Systems))
+	for id := range s.Universe.Systems {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	writeJson(w, ids)
+}
+
+func (s *Server) system(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	sys, ok := s.Universe.Systems[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJson(w, map[string]any{
+		"system_id":        id,
+		"name":             sys.Name,
+		"constellation_id": sys.ConstellationID,
+		"security_status":  sys.SecurityStatus,
+		"stations":         sys.Stations,
+		"stargates":        sys.Stargates,
+	})
+}
+
+func (s *Server) constellation(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	c, ok := s.Universe.Constellations[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJson(w, map[string]any{
+		"constellation_id": id,
+		"name":             c.Name,
+		"region_id":        c.RegionID,
+	})
+}
+
+func (s *Server) regions(w http.ResponseWriter, r *http.Request) {
+	ids := make([]uint32, 0, len(s.Universe.Regions))
+	for id := range s.Universe.Regions {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	writeJson(w, ids)
+}
+
+func (s *Server) region(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	reg, ok := s.Universe.Regions[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJson(w, map[string]any{
+		"region_id": id,
+		"name":      reg.Name,
+	})
+}
+
+func (s *Server) stargate(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	sg, ok := s.Universe.Stargates[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJson(w, map[string]any{
+		"stargate_id": id,
+		"system_id":   sg.SystemID,
+		"position": map[string]any{
+			"x": sg.Position[0],
+			"y": sg.Position[1],
+			"z": sg.Position[2],
+		},
+		"destination": map[string]any{
+			"stargate_id": sg.DestinationGate,
+			"system_id":   sg.Destination,
+		},
+	})
+}
+
+func (s *Server) station(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	st, ok := s.Universe.Stations[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJson(w, map[string]any{
+		"station_id":               id,
+		"name":                     st.Name,
+		"system_id":                st.SystemID,
+		"type_id":                  st.TypeID,
+		"owner":                    st.Owner,
+		"services":                 st.Services,
+		"max_dockable_ship_volume": st.MaxDockableShipVolume,
+	})
+}
+
+func (s *Server) structures(w http.ResponseWriter, r *http.Request) {
+	filter := r.URL.Query().Get("filter")
+	ids := make([]uint64, 0, len(s.Universe.Structures))
+	for id, st := range s.Universe.Structures {
+		switch filter {
+		case "":
+		case "market":
+			if !st.Market {
+				continue
+			}
+		case "manufacturing_basic":
+			if !st.Manufacturing {
+				continue
+			}
+		default:
+			http.Error(w, "invalid filter", http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	writeJson(w, ids)
+}
+
+func (s *Server) structure(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAuth(w, r) {
+		return
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	st, ok := s.Universe.Structures[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJson(w, map[string]any{
+		"name":            st.Name,
+		"owner_id":        st.Owner,
+		"solar_system_id": st.SystemID,
+		"type_id":         st.TypeID,
+	})
+}
+
+// perPage is tiny so the fixture spans several pages.
+const perPage = 2
+
+// paginate returns the page of ids asked for and the page count, answering the errors itself.
+func paginate[T any](w http.ResponseWriter, r *http.Request, ids []T) (page []T, pages int, ok bool) {
+	n := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		var err error
+		n, err = strconv.Atoi(p)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid page", http.StatusBadRequest)
+			return nil, 0, false
+		}
+	}
+	pages = max((len(ids)+perPage-1)/perPage, 1)
+	if n > pages {
+		http.NotFound(w, r)
+		return nil, 0, false
+	}
+	return ids[(n-1)*perPage : min(n*perPage, len(ids))], pages, true
+}
+
+func (s *Server) publicContracts(w http.ResponseWriter, r *http.Request) {
+	region, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := s.Universe.Regions[region]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	var ids []uint32
+	for id, c := range s.Universe.Contracts {
+		if c.Region == region {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	ids, pages, ok := paginate(w, r, ids)
+	if !ok {
+		return
+	}
+
+	contracts := make([]map[string]any, len(ids))
+	for i, id := range ids {
+		c := s.Universe.Contracts[id]
+		contracts[i] = map[string]any{
+			"contract_id":       id,
+			"type":              c.Type,
+			"start_location_id": c.From,
+			"end_location_id":   c.To,
+			"volume":            c.Volume,
+			"reward":            c.Reward,
+			"collateral":        c.Collateral,
+			"date_expired":      c.Expires,
+			"days_to_complete":  c.DaysToComplete,
+		}
+	}
+	w.Header().Set("X-Pages", strconv.Itoa(pages))
+	writeJson(w, contracts)
+}
+
+func (s *Server) marketOrders(w http.ResponseWriter, r *http.Request) {
+	region, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := s.Universe.Regions[region]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	orderType := r.URL.Query().Get("order_type")
+	var ids []uint64
+	for id, o := range s.Universe.Orders {
+		if o.Region != region || orderType == "buy" && !o.Buy || orderType == "sell" && o.Buy {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	ids, pages, ok := paginate(w, r, ids)
+	if !ok {
+		return
+	}
+
+	orders := make([]map[string]any, len(ids))
+	for i, id := range ids {
+		o := s.Universe.Orders[id]
+		orders[i] = map[string]any{
+			"order_id":      id,
+			"type_id":       o.Type,
+			"location_id":   o.Location,
+			"system_id":     s.locationSystem(o.Location),
+			"is_buy_order":  o.Buy,
+			"price":         o.Price,
+			"volume_remain": o.Remain,
+			"volume_total":  o.Remain,
+			"min_volume":    o.MinVolume,
+			"range":         "station",
+			"duration":      90,
+		}
+	}
+	w.Header().Set("X-Pages", strconv.Itoa(pages))
+	writeJson(w, orders)
+}
+
+func (s *Server) locationSystem(location uint64) uint32 {
+	if st, ok := s.Universe.Stations[uint32(location)]; ok {
+		return st.SystemID
+	}
+	return s.Universe.Structures[location].SystemID
+}
+
+func (s *Server) itemType(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	t, ok := s.Universe.Types[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJson(w, map[string]any{
+		"type_id":         id,
+		"name":            t.Name,
+		"volume":          t.Volume,
+		"packaged_volume": t.PackagedVolume,
+	})
+}
+
+func (s *Server) characterLocation(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAuth(w, r) {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if id != CharacterID {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	s.mu.Lock()
+	location := s.location
+	s.mu.Unlock()
+	writeJson(w, map[string]any{
+		"solar_system_id": location,
+	})
+}
+
+func (s *Server) waypoint(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAuth(w, r) {
+		return
+	}
+	q := r.URL.Query()
+	destination, err := strconv.ParseUint(q.Get("destination_id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid destination_id", http.StatusBadRequest)
+		return
+	}
+	_, isSystem := s.Universe.Systems[uint32(destination)]
+	_, isStation := s.Universe.Stations[uint32(destination)]
+	if !isSystem && !isStation {
+		http.Error(w, "unknown destination_id", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.waypoints = append(s.waypoints, Waypoint{
+		DestinationID:       uint32(destination),
+		AddToBeginning:      q.Get("add_to_beginning") == "true",
+		ClearOtherWaypoints: q.Get("clear_other_waypoints") == "true",
+	})
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorize skips the login form and immediately redirects back with a fresh code.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	code := "code-" + strconv.Itoa(s.nextCode)
+	s.nextCode++
+	s.codes[code] = q.Get("code_challenge")
+	s.mu.Unlock()
+
+	back := redirect.Query()
+	back.Set("code", code)
+	back.Set("state", q.Get("state"))
+	redirect.RawQuery = back.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code := r.PostForm.Get("code")
+		s.mu.Lock()
+		challenge, ok := s.codes[code]
+		delete(s.codes, code)
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, "invalid code", http.StatusBadRequest)
+			return
+		}
+		verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(verifier[:]) != challenge {
+			http.Error(w, "code_verifier doesn't match the code_challenge", http.StatusBadRequest)
+			return
+		}
+	case "refresh_token":
+		refresh := r.PostForm.Get("refresh_token")
+		s.mu.Lock()
+		_, ok := s.refreshTokens[refresh]
+		delete(s.refreshTokens, refresh)
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, "invalid refresh_token", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "unsupported grant_type", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	refresh := "refresh-" + strconv.Itoa(s.nextCode)
+	s.nextCode++
+	s.refreshTokens[refresh] = struct{}{}
+	s.mu.Unlock()
+	writeJson(w, map[string]any{
+		"access_token":  s.accessToken(),
+		"token_type":    "Bearer",
+		"expires_in":    1199,
+		"refresh_token": refresh,
+	})
+}
+
+// accessToken is an unsigned JWT carrying only the fields the client reads.
+func (s *Server) accessToken() string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"CHARACTER:EVE:` + strconv.Itoa(CharacterID) + `","name":"Test Pilot"}`))
+	return header + "." + payload + ".fake"
+}
@@ -0,0 +1,195 @@
+package gamelog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// EnvDir overrides the Gamelogs directory discovery.
+const EnvDir = "EVE_GAMELOGS"
+
+// eveAppID is EVE Online's Steam app ID, Proton keeps its prefix under it.
+const eveAppID = "8500"
+
+// Candidates returns where the Gamelogs directory usually is on this machine, most likely first.
+func Candidates() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	logs := filepath.Join("EVE", "logs", "Gamelogs")
+
+	var dirs []string
+	switch runtime.GOOS {
+	case "windows":
+		dirs = append(dirs, filepath.Join(home, "Documents", logs))
+		if od := os.Getenv("OneDrive"); od != "" {
+			dirs = append(dirs, filepath.Join(od, "Documents", logs))
+		}
+	case "darwin":
+		dirs = append(dirs,
+			filepath.Join(home, "Documents", logs),
+			filepath.Join(home, "Library", "Application Support", "EVE Online", "p_drive", "User", "My Documents", logs),
+		)
+	default:
+		user := os.Getenv("USER")
+		for _, steam := range []string{
+			filepath.Join(home, ".steam", "steam"),
+			filepath.Join(home, ".local", "share", "Steam"),
+			filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+		} {
+			dirs = append(dirs, filepath.Join(steam, "steamapps", "compatdata", eveAppID, "pfx", "drive_c", "users", "steamuser", "Documents", logs))
+		}
+		for _, prefix := range []string{
+			filepath.Join(home, ".wine"),
+			filepath.Join(home, "Games", "eve-online"), // Lutris
+		} {
+			dirs = append(dirs,
+				filepath.Join(prefix, "drive_c", "users", user, "Documents", logs),
+				filepath.Join(prefix, "drive_c", "users", user, "My Documents", logs),
+			)
+		}
+		dirs = append(dirs, filepath.Join(home, "Documents", logs))
+	}
+	return dirs
+}
+
+// FindDir returns configured if set, else $EVE_GAMELOGS, else the first existing candidate.
+func FindDir(configured string) (string, error) {
+	if configured == "" {
+		configured = os.Getenv(EnvDir)
+	}
+	if configured != "" {
+		st, err := os.Stat(configured)
+		if err != nil {
+			return "", fmt.Errorf("gamelogs directory: %w", err)
+		}
+		if !st.IsDir() {
+			return "", fmt.Errorf("gamelogs directory: %s is not a directory", configured)
+		}
+		return configured, nil
+	}
+
+	for _, dir := range Candidates() {
+		if st, err := os.Stat(dir); err == nil && st.IsDir() {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("gamelogs directory not found, pass it explicitly or set $%s", EnvDir)
+}
+
+// File is a gamelog and the header the client writes at its top.
+type File struct {
+	Path     string
+	Listener string
+	Started  time.Time
+}
+
+// ReadHeader reads the listener and session start out of the first lines of a gamelog.
+func ReadHeader(path string) (File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return File{}, fmt.Errorf("opening log file: %w", err)
+	}
+	defer f.Close()
+
+	var head [2]byte
+	n, _ := io.ReadFull(f, head[:])
+	r := NewReader(io.MultiReader(bytes.NewReader(head[:n]), f), DetectEncoding(head[:n]))
+
+	file := File{Path: path}
+	for i := 0; i < 8; i++ {
+		raw, _, err := r.ReadLine()
+		if err != nil && err != io.EOF {
+			return File{}, fmt.Errorf("reading log header: %w", err)
+		}
+		line := strings.TrimSpace(string(raw))
+		if listener, ok := strings.CutPrefix(line, "Listener:"); ok {
+			file.Listener = strings.TrimSpace(listener)
+		}
+		if started, ok := strings.CutPrefix(line, "Session Started:"); ok {
+			file.Started, _ = time.Parse("2006.01.02 15:04:05", strings.TrimSpace(started))
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if file.Started.IsZero() {
+		st, err := f.Stat()
+		if err == nil {
+			file.Started = st.ModTime().UTC()
+		}
+	}
+	return file, nil
+}
+
+// Paths returns the paths of the gamelogs in dir without opening them, in file name order.
+func Paths(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("gamelogs directory: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing gamelogs: %w", err)
+	}
+
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".txt") {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, e.Name()))
+	}
+	return paths, nil
+}
+
+// List returns the gamelogs in dir oldest first, only the ones of character unless it is empty.
+func List(dir, character string) ([]File, error) {
+	paths, err := Paths(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	for _, path := range paths {
+		file, err := ReadHeader(path)
+		if err != nil {
+			return nil, err
+		}
+		if character != "" && !strings.EqualFold(file.Listener, character) {
+			continue
+		}
+		files = append(files, file)
+	}
+	slices.SortFunc(files, func(a, b File) int {
+		if c := a.Started.Compare(b.Started); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return files, nil
+}
+
+// LatestListener returns the character of the most recent gamelog, the one currently or last playing.
+// It is empty if dir has no gamelog yet.
+func LatestListener(dir string) (string, error) {
+	files, err := List(dir, "")
+	if err != nil {
+		return "", err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		if files[i].Listener != "" {
+			return files[i].Listener, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,229 @@
+// Package gamelog reads the jumps out of EVE's Gamelogs.
+package gamelog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"eve-tour/universe"
+)
+
+// Jump is one "Jumping from X to Y" line.
+// Time is zero if the line has no timestamp.
+type Jump struct {
+	Time     time.Time
+	From, To uint32
+}
+
+// UnknownSystemError is reported for jump lines naming a system missing from the graph, a wormhole or a highsec only crawl.
+type UnknownSystemError struct {
+	Name string
+}
+
+func (e *UnknownSystemError) Error() string {
+	return "unknown system: " + e.Name
+}
+
+// message is the jump notification of one client language, the system names sit between the parts.
+type message struct {
+	prefix, middle, suffix string
+}
+
+var messages = []message{
+	{"Jumping from ", " to ", ""},  // English
+	{"Sprung von ", " nach ", ""},  // German
+	{"Saut de ", " à ", ""},        // French
+	{"Saut depuis ", " vers ", ""}, // French, older clients
+	{"Прыжок из ", " в ", ""},      // Russian
+	{"", "から", "へジャンプ中"},           // Japanese
+	{"", "から", "へジャンプします"},         // Japanese, older clients
+	{"正在从", "跃迁至", ""},             // Chinese
+	{"从", "跳跃到", ""},               // Chinese, older clients
+}
+
+// Parser matches jump lines against the system names of a graph.
+type Parser struct {
+	nameToID map[string]uint32
+
+	// Warn is called once per unknown system name, the line is skipped either way.
+	// It is nil by default, which keeps quiet.
+	Warn   func(err error)
+	warned map[string]struct{}
+}
+
+func NewParser(g universe.Graph) *Parser {
+	nameToID := make(map[string]uint32, len(g.Nodes))
+	for id, node := range g.Nodes {
+		nameToID[node.Name] = id
+	}
+	return &Parser{
+		nameToID: nameToID,
+		warned:   make(map[string]struct{}),
+	}
+}
+
+// ParseLine returns the jump on line, ok is false for other lines and jumps involving unknown systems.
+func (p *Parser) ParseLine(line []byte) (j Jump, ok bool) {
+	msg, t := splitLine(line)
+	for _, m := range messages {
+		from, to, ok := m.match(msg, p.nameToID)
+		if !ok {
+			continue
+		}
+		fromID, fromOk := p.nameToID[from]
+		toID, toOk := p.nameToID[to]
+		if !fromOk {
+			p.warn(from)
+		}
+		if !toOk {
+			p.warn(to)
+		}
+		if !fromOk || !toOk {
+			return Jump{}, false
+		}
+		return Jump{Time: t, From: fromID, To: toID}, true
+	}
+	return Jump{}, false
+}
+
+func (p *Parser) warn(name string) {
+	if _, ok := p.warned[name]; ok || p.Warn == nil {
+		return
+	}
+	p.warned[name] = struct{}{}
+	p.Warn(&UnknownSystemError{Name: name})
+}
+
+// splitLine cuts "[ 2006.01.02 15:04:05 ] (notify) message" into the message without markup and its time.
+func splitLine(line []byte) (string, time.Time) {
+	var t time.Time
+	line = bytes.TrimSpace(line)
+	if rest, ok := bytes.CutPrefix(line, []byte("[ ")); ok {
+		if stamp, rest, ok := bytes.Cut(rest, []byte(" ]")); ok {
+			// EVE logs are in UTC, same as the in-game clock.
+			t, _ = time.Parse("2006.01.02 15:04:05", string(stamp))
+			line = bytes.TrimSpace(rest)
+		}
+	}
+	if bytes.HasPrefix(line, []byte("(")) {
+		if _, rest, ok := bytes.Cut(line, []byte(")")); ok {
+			line = bytes.TrimSpace(rest)
+		}
+	}
+	return stripTags(line), t
+}
+
+// stripTags removes the <color=...> and <a href=...> markup some clients wrap system names in.
+func stripTags(line []byte) string {
+	if bytes.IndexByte(line, '<') < 0 {
+		return string(line)
+	}
+	var b strings.Builder
+	b.Grow(len(line))
+	for len(line) > 0 {
+		i := bytes.IndexByte(line, '<')
+		if i < 0 {
+			b.Write(line)
+			break
+		}
+		b.Write(line[:i])
+		j := bytes.IndexByte(line[i:], '>')
+		if j < 0 {
+			b.Write(line[i:])
+			break
+		}
+		line = line[i+j+1:]
+	}
+	return b.String()
+}
+
+// match splits msg into the two system names.
+// Names may contain the middle word ("Jumping from A to B to C" is unlikely but legal), so the split where both names exist wins.
+func (m message) match(msg string, names map[string]uint32) (from, to string, ok bool) {
+	if !strings.HasPrefix(msg, m.prefix) || !strings.HasSuffix(msg, m.suffix) || len(msg) < len(m.prefix)+len(m.suffix) {
+		return "", "", false
+	}
+	msg = msg[len(m.prefix) : len(msg)-len(m.suffix)]
+
+	first := -1
+	for i := 0; ; {
+		k := strings.Index(msg[i:], m.middle)
+		if k < 0 {
+			break
+		}
+		i += k
+		if first < 0 {
+			first = i
+		}
+		from, to = msg[:i], msg[i+len(m.middle):]
+		_, fromOk := names[from]
+		_, toOk := names[to]
+		if fromOk && toOk {
+			return from, to, true
+		}
+		i += len(m.middle)
+	}
+	if first < 0 {
+		return "", "", false
+	}
+	return msg[:first], msg[first+len(m.middle):], true
+}
+
+// Scan calls f for every jump in r, a gamelog in any of the encodings the client wrote.
+func (p *Parser) Scan(r io.Reader, f func(Jump)) error {
+	var head [2]byte
+	n, err := io.ReadFull(r, head[:])
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("reading: %w", err)
+	}
+	lr := NewReader(io.MultiReader(bytes.NewReader(head[:n]), r), DetectEncoding(head[:n]))
+	for {
+		line, _, err := lr.ReadLine()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("reading: %w", err)
+		}
+		if j, ok := p.ParseLine(line); ok {
+			f(j)
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// ParseFiles returns the systems jumped through in the given EVE gamelogs.
+// It only outputs systems in the reachable set.
+func ParseFiles(g universe.Graph, logs []string) (map[uint32]struct{}, error) {
+	p := NewParser(g)
+
+	visited := make(map[uint32]struct{})
+	for _, logName := range logs {
+		if err := func() error {
+			f, err := os.Open(logName)
+			if err != nil {
+				return fmt.Errorf("opening log file: %w", err)
+			}
+			defer f.Close()
+
+			return p.Scan(f, func(j Jump) {
+				if _, ok := g.Reachable[j.From]; ok {
+					visited[j.From] = struct{}{}
+				}
+				if _, ok := g.Reachable[j.To]; ok {
+					visited[j.To] = struct{}{}
+				}
+			})
+		}(); err != nil {
+			return nil, err
+		}
+	}
+
+	return visited, nil
+}
@@ -0,0 +1,155 @@
+package gamelog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"eve-tour/universe"
+)
+
+const (
+	jita uint32 = iota + 1
+	perimeter
+	urlen
+	alphaToBeta
+	gamma
+)
+
+func testParser(t *testing.T) *Parser {
+	t.Helper()
+	g := universe.Graph{Nodes: map[uint32]universe.System{
+		jita:        {Name: "Jita"},
+		perimeter:   {Name: "Perimeter"},
+		urlen:       {Name: "Urlen"},
+		alphaToBeta: {Name: "Alpha to Beta"},
+		gamma:       {Name: "Gamma"},
+	}}
+	p := NewParser(g)
+	p.Warn = func(err error) {
+		t.Errorf("unexpected warning: %v", err)
+	}
+	return p
+}
+
+// testdataJumps are the jumps every log in testdata holds, whatever its language or encoding.
+var testdataJumps = []Jump{
+	{Time: time.Date(2026, 10, 18, 12, 1, 2, 0, time.UTC), From: jita, To: perimeter},
+	{Time: time.Date(2026, 10, 18, 12, 5, 0, 0, time.UTC), From: perimeter, To: urlen},
+}
+
+func TestScanTestdata(t *testing.T) {
+	for _, name := range []string{"en.txt", "de.txt", "fr.txt", "ru.txt", "ja.txt", "zh.txt", "utf8-bom.txt", "utf16le-bom.txt"} {
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			var jumps []Jump
+			err = testParser(t).Scan(f, func(j Jump) {
+				jumps = append(jumps, j)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(jumps, testdataJumps) {
+				t.Errorf("got jumps %v, want %v", jumps, testdataJumps)
+			}
+		})
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	stamp := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		line string
+		want Jump
+		ok   bool
+	}{
+		{"english", "[ 2026.10.18 12:00:00 ] (None) Jumping from Jita to Perimeter", Jump{stamp, jita, perimeter}, true},
+		{"german", "[ 2026.10.18 12:00:00 ] (None) Sprung von Jita nach Perimeter", Jump{stamp, jita, perimeter}, true},
+		{"french", "[ 2026.10.18 12:00:00 ] (None) Saut de Jita à Perimeter", Jump{stamp, jita, perimeter}, true},
+		{"french older client", "[ 2026.10.18 12:00:00 ] (None) Saut depuis Jita vers Perimeter", Jump{stamp, jita, perimeter}, true},
+		{"russian", "[ 2026.10.18 12:00:00 ] (None) Прыжок из Jita в Perimeter", Jump{stamp, jita, perimeter}, true},
+		{"japanese", "[ 2026.10.18 12:00:00 ] (None) JitaからPerimeterへジャンプ中", Jump{stamp, jita, perimeter}, true},
+		{"japanese older client", "[ 2026.10.18 12:00:00 ] (None) JitaからPerimeterへジャンプします", Jump{stamp, jita, perimeter}, true},
+		{"chinese", "[ 2026.10.18 12:00:00 ] (None) 正在从Jita跃迁至Perimeter", Jump{stamp, jita, perimeter}, true},
+		{"chinese older client", "[ 2026.10.18 12:00:00 ] (None) 从Jita跳跃到Perimeter", Jump{stamp, jita, perimeter}, true},
+		{"no timestamp", "Jumping from Jita to Perimeter", Jump{From: jita, To: perimeter}, true},
+		{"tagged names", "[ 2026.10.18 12:00:00 ] (None) Jumping from <color=0xffffffff>Jita</color> to <a href=showinfo:5//30000144>Perimeter</a>", Jump{stamp, jita, perimeter}, true},
+		{"name with the middle word", "Jumping from Alpha to Beta to Gamma", Jump{From: alphaToBeta, To: gamma}, true},
+		{"crlf", "Jumping from Jita to Perimeter\r\n", Jump{From: jita, To: perimeter}, true},
+		{"other message", "[ 2026.10.18 12:00:00 ] (notify) Docking at Jita IV - Moon 4 station", Jump{}, false},
+		{"empty", "", Jump{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := testParser(t).ParseLine([]byte(tt.line))
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("got %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseLineWarnsOncePerUnknownSystem(t *testing.T) {
+	p := testParser(t)
+	var warnings []string
+	p.Warn = func(err error) {
+		var unknown *UnknownSystemError
+		if !errors.As(err, &unknown) {
+			t.Errorf("warning %v isn't an UnknownSystemError", err)
+			return
+		}
+		warnings = append(warnings, unknown.Name)
+	}
+	for range 2 {
+		if _, ok := p.ParseLine([]byte("Jumping from Jita to J123456")); ok {
+			t.Error("got a jump into an unknown system")
+		}
+	}
+	if !slices.Equal(warnings, []string{"J123456"}) {
+		t.Errorf("warned about %v, want J123456 once", warnings)
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	for _, name := range []string{"en.txt", "utf8-bom.txt", "utf16le-bom.txt"} {
+		t.Run(name, func(t *testing.T) {
+			f, err := ReadHeader(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+			if f.Listener != "Test Pilot" || !f.Started.Equal(want) {
+				t.Errorf("got %+v, want Test Pilot started at %v", f, want)
+			}
+		})
+	}
+}
+
+func TestPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"20261018_120000.txt", "20261017_090000.TXT", "notes.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "old.txt"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := Paths(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "20261017_090000.TXT"), filepath.Join(dir, "20261018_120000.txt")}
+	if !slices.Equal(paths, want) {
+		t.Errorf("got %v, want %v", paths, want)
+	}
+}
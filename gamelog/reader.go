@@ -0,0 +1,104 @@
+package gamelog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding of a gamelog, older clients wrote UTF-16 with a byte order mark.
+type Encoding uint8
+
+const (
+	UTF8 Encoding = iota
+	UTF16LE
+	UTF16BE
+)
+
+// DetectEncoding looks at the first bytes of a file for a UTF-16 byte order mark.
+func DetectEncoding(head []byte) Encoding {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xff, 0xfe}):
+		return UTF16LE
+	case bytes.HasPrefix(head, []byte{0xfe, 0xff}):
+		return UTF16BE
+	}
+	return UTF8
+}
+
+// Reader splits a gamelog into UTF-8 lines.
+type Reader struct {
+	r   *bufio.Reader
+	enc Encoding
+
+	buf   []byte
+	units []uint16
+}
+
+func NewReader(r io.Reader, enc Encoding) *Reader {
+	return &Reader{r: bufio.NewReaderSize(r, 1024*1024), enc: enc}
+}
+
+// ReadLine returns the next line without its line ending, byte order mark or invalid UTF-8, and how many bytes of the file it spanned.
+// A last line without a newline is returned along with io.EOF, callers tailing a log still being written should drop it and resume at the line boundary.
+// The returned line is only valid until the next call.
+func (r *Reader) ReadLine() (line []byte, n int, err error) {
+	if r.enc == UTF8 {
+		raw, err := r.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// Absurdly long line, keep going so offsets stay right.
+			r.buf = append(r.buf[:0], raw...)
+			for err == bufio.ErrBufferFull {
+				raw, err = r.r.ReadSlice('\n')
+				r.buf = append(r.buf, raw...)
+			}
+			raw = r.buf
+		}
+		if err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		n = len(raw)
+		line = bytes.TrimPrefix(raw, []byte("\uFEFF"))
+		line = bytes.TrimRight(line, "\r\n")
+		if !utf8.Valid(line) {
+			line = bytes.ToValidUTF8(line, nil)
+		}
+		return line, n, err
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if r.enc == UTF16BE {
+		order = binary.BigEndian
+	}
+	r.units = r.units[:0]
+	var pair [2]byte
+	for {
+		_, err = io.ReadFull(r.r, pair[:])
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		if err != nil {
+			break
+		}
+		n += 2
+		unit := order.Uint16(pair[:])
+		if unit == '\n' {
+			break
+		}
+		if unit == 0xfeff || unit == '\r' {
+			continue
+		}
+		r.units = append(r.units, unit)
+	}
+	if err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	r.buf = r.buf[:0]
+	for _, c := range utf16.Decode(r.units) {
+		r.buf = utf8.AppendRune(r.buf, c)
+	}
+	return r.buf, n, err
+}
@@ -0,0 +1,109 @@
+package gamelog
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// readLines reads r like a tail would: the last line is dropped if it has no newline yet.
+// offset is where the next read should resume.
+func readLines(t *testing.T, r *Reader) (lines []string, offset int) {
+	t.Helper()
+	for {
+		line, n, err := r.ReadLine()
+		if err == io.EOF {
+			return lines, offset
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(line))
+		offset += n
+	}
+}
+
+func readFile(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		head []byte
+		want Encoding
+	}{
+		{[]byte{0xff, 0xfe}, UTF16LE},
+		{[]byte{0xfe, 0xff}, UTF16BE},
+		{[]byte{0xef, 0xbb}, UTF8},
+		{[]byte("--"), UTF8},
+		{nil, UTF8},
+	}
+	for _, tt := range tests {
+		if got := DetectEncoding(tt.head); got != tt.want {
+			t.Errorf("DetectEncoding(% x) = %d, want %d", tt.head, got, tt.want)
+		}
+	}
+}
+
+func TestReaderEncodings(t *testing.T) {
+	en := readFile(t, "en.txt")
+	want, offset := readLines(t, NewReader(bytes.NewReader(en), UTF8))
+	if offset != len(en) {
+		t.Fatalf("lines span %d bytes of %d", offset, len(en))
+	}
+
+	for _, name := range []string{"utf8-bom.txt", "utf16le-bom.txt"} {
+		t.Run(name, func(t *testing.T) {
+			b := readFile(t, name)
+			lines, offset := readLines(t, NewReader(bytes.NewReader(b), DetectEncoding(b)))
+			if !slices.Equal(lines, want) {
+				t.Errorf("got lines %q, want %q without byte order mark or carriage returns", lines, want)
+			}
+			if offset != len(b) {
+				t.Errorf("lines span %d bytes of %d", offset, len(b))
+			}
+		})
+	}
+}
+
+func TestReaderBigEndian(t *testing.T) {
+	b := []byte{0xfe, 0xff, 0, 'h', 0, 'i', 0, '\r', 0, '\n', 0x30, 0x42, 0, '\n'}
+	lines, offset := readLines(t, NewReader(bytes.NewReader(b), DetectEncoding(b)))
+	if !slices.Equal(lines, []string{"hi", "あ"}) || offset != len(b) {
+		t.Errorf("got %q spanning %d bytes, want hi and あ spanning %d", lines, offset, len(b))
+	}
+}
+
+func TestReaderInvalidUTF8(t *testing.T) {
+	lines, _ := readLines(t, NewReader(bytes.NewReader([]byte("a\xffb\n")), UTF8))
+	if !slices.Equal(lines, []string{"ab"}) {
+		t.Errorf("got %q, want the invalid byte dropped", lines)
+	}
+}
+
+// TestReaderResume reads a log cut at every byte, as if the client was still writing it, and resumes at the returned offset once it is complete.
+func TestReaderResume(t *testing.T) {
+	for _, name := range []string{"en.txt", "ja.txt", "utf8-bom.txt", "utf16le-bom.txt"} {
+		t.Run(name, func(t *testing.T) {
+			full := readFile(t, name)
+			enc := DetectEncoding(full)
+			want, _ := readLines(t, NewReader(bytes.NewReader(full), enc))
+
+			for cut := range len(full) {
+				first, offset := readLines(t, NewReader(bytes.NewReader(full[:cut]), enc))
+				rest, _ := readLines(t, NewReader(bytes.NewReader(full[offset:]), enc))
+				if got := append(first, rest...); !slices.Equal(got, want) {
+					t.Fatalf("cut at %d, resumed at %d: got %q, want %q", cut, offset, got, want)
+				}
+			}
+		})
+	}
+}
@@ -2,511 +2,527 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"io"
+	"log"
+	"math"
 	"os"
-	"os/exec"
-	"regexp"
-	"strconv"
-	"strings"
+	"os/signal"
+	"time"
+
+	"eve-tour/distance"
+	"eve-tour/esi"
+	"eve-tour/gamelog"
+	"eve-tour/query"
+	"eve-tour/route"
+	"eve-tour/travel"
+	"eve-tour/universe"
+	"eve-tour/visited"
 )
 
+// logger gets the progress and warnings of the packages doing the work.
+var logger = log.New(os.Stdout, "", 0)
+
 func run() error {
 	var onlySearchThesesRegions string
 	flag.StringVar(&onlySearchThesesRegions, "regions", "", "Only search for systems in theses regions, separated by commas. Note, the path finder will still route through other regions if it's faster.")
 	var doNotSearchThesesRegions string
 	flag.StringVar(&doNotSearchThesesRegions, "skip-regions", "", "Regions exclude from search, separated by commas. Note, the path finder will still route through this region if it's faster.")
+	var onlySearchThesesConstellations string
+	flag.StringVar(&onlySearchThesesConstellations, "constellations", "", "Only search for systems in theses constellations, separated by commas.")
+	var doNotSearchThesesConstellations string
+	flag.StringVar(&doNotSearchThesesConstellations, "skip-constellations", "", "Constellations exclude from search, separated by commas.")
 	var onlyHighsec bool
 	flag.BoolVar(&onlyHighsec, "highsec", false, "Only search for systems in highsec.")
 	var gtsp bool
-	flag.BoolVar(&gtsp, "gtsp", false, "Used colored TSP algorithm, clustering by region.")
+	flag.BoolVar(&gtsp, "gtsp", false, "Used colored TSP algorithm, clustering by -gtsp-clusters.")
+	var clusters string
+	flag.StringVar(&clusters, "gtsp-clusters", "region", "What -gtsp visits one node of: region, constellation, station (one station of each system), owner (one station of each NPC corporation) or groups (each group of -gtsp-groups at least once).")
+	var groupsFile string
+	flag.StringVar(&groupsFile, "gtsp-groups", "", "File of groups for -gtsp-clusters groups, one per line as \"name: system, system, ...\".")
 	var countCostOfStartSystem bool
 	flag.BoolVar(&countCostOfStartSystem, "start", false, "Include the travel costs from your current system.")
 	var onlyWithStations bool
 	flag.BoolVar(&onlyWithStations, "stations", false, "Only search for systems with stations.")
+	var structures bool
+	flag.BoolVar(&structures, "structures", false, "Also consider public Upwell structures in service queries, cached in "+universe.StructuresFile+". Needs a login.")
+	var querySrc string
+	flag.StringVar(&querySrc, "query", "", `Only search for systems matching this expression, for example "region in ('The Forge', Lonetrek) and security >= 0.5 and jumps_from(highsec) <= 2". See the README for the fields and functions.`)
+	var crawlWorkers int
+	flag.IntVar(&crawlWorkers, "crawl-workers", 8, "Number of concurrent requests used when downloading the starmap.")
+	var crawlRate float64
+	flag.Float64Var(&crawlRate, "crawl-rate", 20, "Maximum requests per second sent to ESI, 0 disables the limit.")
+	var shipProfile string
+	flag.StringVar(&shipProfile, "ship-profile", "", "Optimize for travel time with this ship profile (shuttle, interceptor, frigate, destroyer, cruiser, battlecruiser, battleship, industrial, freighter) instead of jump counts.")
+	var gateGraph bool
+	flag.BoolVar(&gateGraph, "gate-graph", false, "With -ship-profile, compute travel times on the stargate level graph which knows the warp between the gate you arrive from and the one you leave by.")
+	var character string
+	flag.StringVar(&character, "character", "", "Character whose visited systems are remembered, see the visited command. Defaults to the character of the most recent gamelog.")
+	var gamelogsDir string
+	flag.StringVar(&gamelogsDir, "gamelogs", "", "EVE's Gamelogs directory, read when no log files are given. Found automatically on Windows, Wine and Proton installs or with $"+gamelog.EnvDir+".")
+	var constraints []string
+	flag.Func("before", `Visit some targets ahead of others, as "A before B" where A and B are system names or queries, like "region = 'The Forge' before region = Lonetrek". Repeatable.`, func(s string) error {
+		constraints = append(constraints, s)
+		return nil
+	})
+	var precedenceFile string
+	flag.StringVar(&precedenceFile, "precedence-file", "", "File of -before constraints, one per line, # starts a comment.")
+	var budget uint64
+	flag.Uint64Var(&budget, "budget", 0, "Only visit the targets worth the most within this many jumps, or seconds with -ship-profile, counted from your current system with -start. 0 visits every target.")
+	var scoresFile string
+	flag.StringVar(&scoresFile, "scores", "", "File of \"system: score\" lines for -budget, targets not listed are worth 1.")
+	var lastTour string
+	flag.StringVar(&lastTour, "last-tour", route.LastTourFile, "Where the solution is saved, the next run starts LKH from it without the systems visited since. Empty solves from scratch.")
+	var parallel int
+	flag.IntVar(&parallel, "parallel", 1, "Run this many LKH instances at once with different seeds and keep the best tour, Ctrl+C stops them and keeps the best one found so far.")
+	var boundIterations int
+	flag.IntVar(&boundIterations, "bound-iterations", 100, "Subgradient steps of the Held-Karp lower bound the tour is compared to, 0 only uses the trivial bounds. Big tours get fewer to keep it quick.")
+	var sessionJumps int
+	flag.IntVar(&sessionJumps, "session-jumps", 0, "Split the route into play sessions of at most this many jumps, each ending in a system with a station. Only the first session is uploaded.")
+	var sessionMinutes int
+	flag.IntVar(&sessionMinutes, "session-minutes", 0, "Split the route into play sessions of at most this many minutes of travel, needs a -ship-profile.")
+	var safeSystems string
+	flag.StringVar(&safeSystems, "safe-systems", "", "Extra systems sessions may end in, separated by commas, for safe spots without stations.")
+	var navigate bool
+	flag.BoolVar(&navigate, "navigate", false, "Keep only the next -navigate-waypoints waypoints set and add more as ESI reports you reaching them, planning again when you stray or get podded.")
+	var navigateWaypoints int
+	flag.IntVar(&navigateWaypoints, "navigate-waypoints", 10, "How many waypoints -navigate keeps set in game.")
+	var navigateInterval time.Duration
+	flag.DurationVar(&navigateInterval, "navigate-interval", 10*time.Second, "How often -navigate polls your location, ESI caches it for 5 seconds.")
+	var watch bool
+	flag.BoolVar(&watch, "watch", false, "Keep following the gamelogs after uploading the route, dropping visited systems and planning again when you leave the route.")
+	var watchInterval time.Duration
+	flag.DurationVar(&watchInterval, "watch-interval", 5*time.Second, "How often -watch looks at the gamelogs.")
+	client := esi.NewClient()
+	flag.StringVar(&client.BaseURL, "esi-url", client.BaseURL, "Base URL of the ESI API.")
+	flag.StringVar(&client.SSOURL, "sso-url", client.SSOURL, "Base URL of the EVE SSO.")
 	flag.Parse()
-	var onlyThesesRegions map[string]struct{}
-	if onlySearchThesesRegions != "" {
-		onlyThesesRegions = make(map[string]struct{})
-		for region := range strings.SplitSeq(onlySearchThesesRegions, ",") {
-			onlyThesesRegions[strings.ToLower(strings.TrimSpace(region))] = struct{}{}
-		}
+	client.Limiter.SetRate(crawlRate)
+	clustering, err := route.ParseClustering(clusters)
+	if err != nil {
+		return err
 	}
-	var doNotSearchRegions map[string]struct{}
-	if doNotSearchThesesRegions != "" {
-		doNotSearchRegions = make(map[string]struct{})
-		for region := range strings.SplitSeq(doNotSearchThesesRegions, ",") {
-			doNotSearchRegions[strings.ToLower(strings.TrimSpace(region))] = struct{}{}
-		}
+	if (clustering == route.ByGroups) != (groupsFile != "") {
+		return fmt.Errorf("-gtsp-groups goes with -gtsp-clusters groups")
 	}
 
-	g, err := loadOrCreateMap(onlyHighsec)
-	if err != nil {
-		return fmt.Errorf("failed to load graph: %w", err)
+	var q *query.Query
+	if querySrc != "" {
+		q, err = query.Parse(querySrc)
+		if err != nil {
+			return err
+		}
 	}
 
-	visited, err := parseAlreadyVisitedSystems(g)
+	g, err := universe.LoadOrCreate(client, universe.CrawlOptions{
+		OnlyHighsec: onlyHighsec,
+		Workers:     crawlWorkers,
+		Log:         logger,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to parse already visited systems: %w", err)
+		return fmt.Errorf("failed to load graph: %w", err)
 	}
 
-	// Now that we have the full matrix, remove all the systems we don't care about.
-	var neededInComputeMatrix []uint32
-	var gtspBuckets [][]uint
-	var gtspSystemsToMatrix map[string]uint
-	if gtsp {
-		gtspSystemsToMatrix = make(map[string]uint)
-	}
-	for _, v := range g.MatrixIndexesToIds {
-		if _, ok := visited[v]; ok {
-			continue
-		}
-		system := g.Nodes[v]
-		if onlyThesesRegions != nil {
-			if _, ok := onlyThesesRegions[strings.ToLower(system.Region)]; !ok {
-				continue
-			}
+	logs := flag.Args()
+	var dir string
+	if len(logs) == 0 || watch {
+		dir, err = findGamelogs(gamelogsDir, &character, watch)
+		if err != nil {
+			return err
 		}
-		if doNotSearchRegions != nil {
-			if _, ok := doNotSearchRegions[strings.ToLower(system.Region)]; ok {
-				continue
+		if len(logs) == 0 {
+			logs, err = characterLogs(dir, character)
+			if err != nil {
+				return err
 			}
 		}
-		if onlyWithStations && len(system.Stations) == 0 {
-			continue
-		}
+	}
 
-		matrixId := uint(len(neededInComputeMatrix))
-		neededInComputeMatrix = append(neededInComputeMatrix, v)
-		if gtsp {
-			region := g.Nodes[v].Region
-			regionIndex, ok := gtspSystemsToMatrix[region]
-			if !ok {
-				regionIndex = uint(len(gtspBuckets))
-				gtspSystemsToMatrix[region] = regionIndex
-				gtspBuckets = append(gtspBuckets, nil)
+	opts := planOptions{
+		gtsp:                   gtsp,
+		clustering:             clustering,
+		countCostOfStartSystem: countCostOfStartSystem,
+		session:                &esi.Session{Client: client},
+	}
+	if structures {
+		s, ok, err := universe.LoadStructures(universe.StructuresFile)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			token, _, err := opts.session.Token()
+			if err != nil {
+				return err
+			}
+			s, err = universe.FetchStructures(client, token, crawlWorkers, logger)
+			if err != nil {
+				return fmt.Errorf("failed to fetch structures: %w", err)
+			}
+			err = universe.SaveStructures(universe.StructuresFile, s)
+			if err != nil {
+				return err
 			}
-			gtspBuckets[regionIndex] = append(gtspBuckets[regionIndex], matrixId)
 		}
+		g.AddStructures(s)
 	}
 
-	computeMatrixIdToDistanceId := make([]uint, len(neededInComputeMatrix))
-	for i, v := range neededInComputeMatrix {
-		computeMatrixIdToDistanceId[i] = g.IdsToMatrixIndexes[v]
+	store, err := visited.Open(character)
+	if err != nil {
+		return err
 	}
-
-	compute := NewD2(uint(len(neededInComputeMatrix)))
-	for i := range uint(len(neededInComputeMatrix)) {
-		for j := range uint(len(neededInComputeMatrix)) {
-			compute.Set(i, j, g.Matrix.At(computeMatrixIdToDistanceId[i], computeMatrixIdToDistanceId[j]))
-		}
+	err = ingestLogs(g, store, logs)
+	if err != nil {
+		return fmt.Errorf("failed to parse already visited systems: %w", err)
 	}
 
-	if gtsp {
-		err = copyFile("graph.par", "GLKH/graph.par")
+	// Now that we have the full matrix, remove all the systems we don't care about.
+	filter := route.Filter{
+		Regions:            route.ParseNames(onlySearchThesesRegions),
+		SkipRegions:        route.ParseNames(doNotSearchThesesRegions),
+		Constellations:     route.ParseNames(onlySearchThesesConstellations),
+		SkipConstellations: route.ParseNames(doNotSearchThesesConstellations),
+		OnlyWithStations:   onlyWithStations,
+		Visited:            store.Set(),
+	}
+	if q != nil {
+		filter.Predicate, err = q.Bind(g)
 		if err != nil {
-			return fmt.Errorf("failed to copy graph.par: %w", err)
+			return err
 		}
-
-		err = outputGtspFile("GLKH/graph.tsp", compute, gtspBuckets)
+	}
+	targets := route.Targets(g, filter)
+	if groupsFile != "" {
+		opts.groups, err = route.ReadGroups(g, groupsFile)
 		if err != nil {
-			return fmt.Errorf("failed to output GTSP file: %w", err)
+			return err
 		}
-
-		cmd := exec.Command("./GLKH", "graph.par")
-		cmd.Dir = "GLKH"
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		err = cmd.Run()
+	}
+	if scoresFile != "" {
+		if budget == 0 {
+			return fmt.Errorf("-scores goes with -budget")
+		}
+		opts.scores, err = route.ReadScores(g, scoresFile)
 		if err != nil {
-			return fmt.Errorf("running GLKH: %w", err)
+			return err
 		}
-
-		systemsAsMatrixIds, err := loadSolution("GLKH/output.tour", false)
+	}
+	opts.budget = budget
+	opts.lastTour = lastTour
+	opts.boundIterations = boundIterations
+	opts.parallel = parallel
+	if sessionMinutes != 0 && shipProfile == "" {
+		return fmt.Errorf("-session-minutes needs a -ship-profile")
+	}
+	opts.sessions = route.SessionLimits{Jumps: sessionJumps, Seconds: uint64(sessionMinutes) * 60}
+	for name := range route.ParseNames(safeSystems) {
+		id, err := lookupSystem(g, name)
 		if err != nil {
-			return fmt.Errorf("failed to load GLKH solution: %w", err)
+			return err
 		}
-
-		// make a new compute matrix with the results of GLKH for HPP to improve further
-		neededInComputeMatrixNarrow := make([]uint32, len(systemsAsMatrixIds))
-		computeMatrixIdToDistanceIdNarrow := make([]uint, len(systemsAsMatrixIds))
-		computeNarrow := NewD2(uint(len(systemsAsMatrixIds)))
-		for i, v := range systemsAsMatrixIds {
-			neededInComputeMatrixNarrow[i] = neededInComputeMatrix[v]
-			computeMatrixIdToDistanceIdNarrow[i] = computeMatrixIdToDistanceId[v]
-			for j, v2 := range systemsAsMatrixIds {
-				computeNarrow.Set(uint(i), uint(j), compute.At(v, v2))
-			}
+		if opts.safe == nil {
+			opts.safe = make(map[uint32]struct{})
 		}
-		neededInComputeMatrix = neededInComputeMatrixNarrow
-		computeMatrixIdToDistanceId = computeMatrixIdToDistanceIdNarrow
-		compute = computeNarrow
+		opts.safe[id] = struct{}{}
 	}
-
-	var token string
-	var firstHopCosts []uint8
-	if countCostOfStartSystem {
-		var userId uint32
-		token, userId, err = grabUserToken()
+	if precedenceFile != "" {
+		opts.constraints, err = route.ReadConstraints(g, precedenceFile)
 		if err != nil {
-			return fmt.Errorf("failed to grab user token: %w", err)
+			return err
 		}
-		startSystem, err := getLocation(token, userId)
+	}
+	for _, text := range constraints {
+		c, err := route.ParseConstraint(g, text)
 		if err != nil {
-			return fmt.Errorf("failed to get location: %w", err)
+			return err
 		}
-		startSystemMatrixIndex, ok := g.IdsToMatrixIndexes[startSystem]
-		if !ok {
-			return fmt.Errorf("start system %d not in matrix", startSystem)
+		opts.constraints = append(opts.constraints, c)
+	}
+	if navigate {
+		if watch {
+			return fmt.Errorf("-navigate and -watch both follow the route, pick one")
 		}
-
-		firstHopCosts = make([]uint8, len(computeMatrixIdToDistanceId))
-		for i, v := range computeMatrixIdToDistanceId {
-			firstHopCosts[i] = uint8(g.Matrix.At(startSystemMatrixIndex, v))
+		opts.navigate = &navigation{
+			waypoints: max(navigateWaypoints, 1),
+			interval:  navigateInterval,
+			store:     store,
 		}
 	}
-
-	err = copyFile("graph.par", "LKH/graph.par")
-	if err != nil {
-		return fmt.Errorf("failed to copy graph.par: %w", err)
-	}
-
-	err = outputSopFile("LKH/graph.tsp", compute, firstHopCosts)
-	if err != nil {
-		return fmt.Errorf("failed to output TSP file: %w", err)
-	}
-
-	cmd := exec.Command("./LKH", "graph.par")
-	cmd.Dir = "LKH"
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	err = cmd.Run()
-	if err != nil {
-		return fmt.Errorf("running LKH: %w", err)
-	}
-
-	systemsAsMatrixIds, err := loadSolution("LKH/output.tour", true)
-	if err != nil {
-		return fmt.Errorf("failed to load GLKH solution: %w", err)
-	}
-
-	var solutionAsIds []uint32
-	for _, v := range systemsAsMatrixIds {
-		solutionAsIds = append(solutionAsIds, neededInComputeMatrix[v])
-	}
-
-	err = writeOutput(g, solutionAsIds)
-	if err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
-	}
-
-	if token == "" {
-		token, _, err = grabUserToken()
+	if watch {
+		opts.watch, err = newWatcher(g, store, dir, character, watchInterval)
 		if err != nil {
-			return fmt.Errorf("failed to grab user token: %w", err)
+			return err
 		}
 	}
 
-	err = addWaypoints(g, token, solutionAsIds)
-	if err != nil {
-		return fmt.Errorf("failed to add waypoints to UI: %w", err)
+	if shipProfile == "" {
+		if gateGraph {
+			return fmt.Errorf("-gate-graph needs a -ship-profile")
+		}
+		return plan(client, g, route.NewProblem(g, targets), opts)
 	}
-
-	return nil
-}
-
-func copyFile(src, dst string) error {
-	srcFile, err := os.Open(src)
+	profile, err := travel.Profile(shipProfile)
 	if err != nil {
-		return fmt.Errorf("opening src file: %w", err)
+		return err
 	}
-	defer srcFile.Close()
-
-	dstFile, err := os.Create(dst)
-	if err != nil {
-		return fmt.Errorf("creating dst file: %w", err)
+	var times distance.Matrix[uint16]
+	if gateGraph {
+		var gg travel.GateGraph
+		gg, err = travel.NewGateGraph(g, profile)
+		if err == nil {
+			times = gg.SystemMatrix(g)
+		}
+	} else {
+		times, err = travel.Matrix(g, profile)
 	}
-	defer dstFile.Close()
-
-	_, err = io.CopyBuffer(dstFile, srcFile, make([]byte, 1024*1024*32))
 	if err != nil {
-		os.Remove(dst)
-		return fmt.Errorf("copying: %w", err)
+		return fmt.Errorf("failed to compute travel times: %w", err)
 	}
-
-	return nil
+	return plan(client, g, route.NewWeightedProblem(g, times, targets), opts)
 }
 
-func outputGtspFile(filepath string, distances D2, gtspBuckets [][]uint) error {
-	file, err := os.Create(filepath)
-	if err != nil {
-		return fmt.Errorf("creating: %w", err)
-	}
-	defer file.Close()
-
-	w := bufio.NewWriterSize(file, 1024*1024*32)
-
-	_, err = fmt.Fprintf(w, `TYPE: GTSP
-GTSP_SETS: %d
-EDGE_WEIGHT_TYPE: EXPLICIT
-EDGE_WEIGHT_FORMAT: FULL_MATRIX
-DIMENSION: %d
-EDGE_WEIGHT_SECTION
-`, len(gtspBuckets), distances.RowSize)
-	if err != nil {
-		return fmt.Errorf("writing: %w", err)
-	}
+type planOptions struct {
+	gtsp                   bool
+	clustering             route.Clustering
+	groups                 []route.Group
+	countCostOfStartSystem bool
+	constraints            []route.Constraint
+	// budget is 0 unless only the best targets within it are visited, scores are what they are worth.
+	budget uint64
+	scores map[uint32]float64
+	// parallel is how many solver instances run at once.
+	parallel int
+	// boundIterations is how hard to look for a lower bound to compare the tour to.
+	boundIterations int
+	// lastTour is the file the previous solution is read from and the new one saved to, empty to solve from scratch.
+	lastTour string
+	// sessions is zero unless the route is split, safe are the extra systems sessions can end in.
+	sessions route.SessionLimits
+	safe     map[uint32]struct{}
+	// watch is nil unless -watch is set, navigate unless -navigate is.
+	watch    *watcher
+	navigate *navigation
+	// session logs in the first time a token is needed and keeps it fresh for -watch and -navigate.
+	session *esi.Session
+}
 
-	var recycled []byte
-	for i := range distances.RowSize {
-		for j := range distances.RowSize {
-			if j > 0 {
-				err := w.WriteByte(' ')
-				if err != nil {
-					return fmt.Errorf("writing: %w", err)
-				}
-			}
-			recycled = strconv.AppendUint(recycled[:0], uint64(distances.At(i, j)), 10)
-			_, err := w.Write(recycled)
-			if err != nil {
-				return fmt.Errorf("writing: %w", err)
-			}
+// plan solves the problem and uploads the route, T is uint8 for jump counts and wider for weighted costs.
+func plan[T distance.Weight](client *esi.Client, g universe.Graph, problem route.Problem[T], opts planOptions) error {
+	var err error
+	lkh, glkh := route.LKH, route.GLKH
+	lkh.Parallel, glkh.Parallel = opts.parallel, opts.parallel
+	lkh.Log, glkh.Log = logger, logger
+	if opts.gtsp {
+		var buckets [][]uint
+		problem, buckets, err = route.Clusters(g, problem, opts.clustering, opts.groups, logger)
+		if err != nil {
+			return err
 		}
-		err := w.WriteByte('\n')
+		// make a new compute matrix with the results of GLKH for HPP to improve further
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		problem, err = route.SolveGTSP(ctx, glkh, problem, buckets)
+		stop()
 		if err != nil {
-			return fmt.Errorf("writing: %w", err)
+			return fmt.Errorf("failed to solve GTSP: %w", err)
 		}
+		problem = problem.Dedup()
 	}
 
-	_, err = w.WriteString("GTSP_SET_SECTION\n")
-	if err != nil {
-		return fmt.Errorf("writing: %w", err)
-	}
-	for i, bucket := range gtspBuckets {
-		recycled = strconv.AppendUint(recycled[:0], uint64(i+1), 10) // LKH is one-indexed
-		_, err := w.Write(recycled)
+	var firstHopCosts []T
+	var startSystem uint32
+	if opts.countCostOfStartSystem {
+		token, userId, err := opts.session.Token()
 		if err != nil {
-			return fmt.Errorf("writing: %w", err)
+			return err
 		}
-		for _, v := range bucket {
-			recycled = append(recycled[:0], ' ')
-			recycled = strconv.AppendUint(recycled, uint64(v+1), 10) // LKH is one-indexed
-			_, err := w.Write(recycled)
-			if err != nil {
-				return fmt.Errorf("writing: %w", err)
-			}
+		startSystem, err = client.Location(token, userId)
+		if err != nil {
+			return fmt.Errorf("failed to get location: %w", err)
 		}
-		_, err = w.WriteString(" -1\n")
+		firstHopCosts, err = problem.FirstHopCosts(g, startSystem)
 		if err != nil {
-			return fmt.Errorf("writing: %w", err)
+			return err
 		}
 	}
 
-	_, err = w.WriteString("EOF\n")
-	if err != nil {
-		return fmt.Errorf("writing: %w", err)
+	if opts.budget != 0 {
+		scores := problem.Scores(opts.scores)
+		order := route.Orienteer(problem, firstHopCosts, scores, opts.budget)
+		if len(order) == 0 {
+			return fmt.Errorf("no target within a budget of %d", opts.budget)
+		}
+		var score float64
+		for _, i := range order {
+			score += scores[i]
+		}
+		fmt.Println("picked", len(order), "targets worth", score, "for", problem.PathCost(order, firstHopCosts), "of a budget of", opts.budget)
+		problem = problem.Narrow(order)
+		if firstHopCosts != nil {
+			firstHopCosts, err = problem.FirstHopCosts(g, startSystem)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	err = w.Flush()
+	precedences, err := problem.Precedences(g, opts.constraints, logger)
 	if err != nil {
-		return fmt.Errorf("flushing: %w", err)
+		return err
 	}
-
-	return nil
-}
-
-func outputSopFile(filepath string, distances D2, firstHopCosts []uint8) error {
-	file, err := os.Create(filepath)
-	if err != nil {
-		return fmt.Errorf("creating: %w", err)
+	var initial []uint
+	if opts.budget != 0 {
+		// start LKH from the picked path, Narrow kept the orienteer's order
+		initial = make([]uint, len(problem.Systems))
+		for i := range initial {
+			initial[i] = uint(i)
+		}
+	} else if opts.lastTour != "" {
+		last, ok, err := route.LoadLastTour(opts.lastTour)
+		if err != nil {
+			return err
+		}
+		if ok {
+			var kept int
+			initial, kept = problem.Seed(g, last, firstHopCosts)
+			if initial != nil {
+				fmt.Println("starting from the last tour,", kept, "of", len(initial), "targets already placed")
+			}
+		}
 	}
-	defer file.Close()
-
-	w := bufio.NewWriterSize(file, 1024*1024*32)
-
-	distanceWithFakeStartAndEnd := distances.RowSize + 2
-	_, err = fmt.Fprintf(w, `TYPE: SOP
-EDGE_WEIGHT_TYPE: EXPLICIT
-EDGE_WEIGHT_FORMAT: FULL_MATRIX
-DIMENSION: %d
-EDGE_WEIGHT_SECTION
-%d
-`, distanceWithFakeStartAndEnd, distanceWithFakeStartAndEnd)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	order, err := route.SolveSOPOrder(ctx, lkh, problem, firstHopCosts, precedences, initial)
+	stop()
 	if err != nil {
-		return fmt.Errorf("writing: %w", err)
+		return fmt.Errorf("failed to solve SOP: %w", err)
 	}
-
-	var recycled []byte
-	if firstHopCosts == nil {
-		firstHopCosts = make([]uint8, distances.RowSize) // we allow the searcher to begin wherever it wants
-	}
-	_, err = w.WriteString("0 ") // zeroth's diagonal
+	report, err := problem.Report(g, order, firstHopCosts, startSystem)
 	if err != nil {
-		return fmt.Errorf("writing: %w", err)
-	}
-	for _, v := range firstHopCosts {
-		recycled = strconv.AppendUint(recycled[:0], uint64(v), 10)
-		recycled = append(recycled, ' ')
-		_, err = w.Write(recycled)
+		return fmt.Errorf("invalid solution: %w", err)
+	}
+	printReport(g, report)
+	bounds := problem.LowerBounds(firstHopCosts, opts.boundIterations, report.Cost)
+	printGap(bounds, report.Cost)
+	solution := problem.Narrow(order)
+	if opts.lastTour != "" {
+		err = route.SaveLastTour(opts.lastTour, solution.LastTour())
 		if err != nil {
-			return fmt.Errorf("writing: %w", err)
+			return err
 		}
 	}
-	_, err = w.WriteString("-1\n") // can't skip the whole thing straight to the end
+	if opts.budget != 0 {
+		// LKH started from the picked path and only keeps shorter ones, unless precedences forced a detour
+		if report.Cost > opts.budget {
+			fmt.Println("warning: the route costs", report.Cost, "which is over the budget of", opts.budget)
+		}
+	}
+	solutionAsIds := solution.Systems
+
+	err = writeOutput(g, solutionAsIds, solution.Stations)
 	if err != nil {
-		return fmt.Errorf("writing: %w", err)
+		return fmt.Errorf("failed to write output: %w", err)
 	}
 
-	for i := range distances.RowSize {
-		_, err = w.WriteString("-1 ")
-		if err != nil {
-			return fmt.Errorf("writing: %w", err)
+	waypoints := solution.Waypoints()
+	// targets are the systems uploaded, with the end of the first session if it's a detour
+	targets := solutionAsIds
+	if opts.sessions != (route.SessionLimits{}) {
+		var times *distance.Matrix[T]
+		if opts.sessions.Seconds != 0 {
+			times = &problem.Full
 		}
-		for j := range distances.RowSize {
-			recycled = strconv.AppendUint(recycled[:0], uint64(distances.At(i, j)), 10)
-			recycled = append(recycled, ' ')
-			_, err = w.Write(recycled)
-			if err != nil {
-				return fmt.Errorf("writing: %w", err)
-			}
-		}
-		_, err = w.WriteString("0\n")
+		sessions := route.Sessions(g, times, solutionAsIds, startSystem, route.Safe(g, opts.safe), opts.sessions)
+		err = writeSessions(g, solution.Systems, solution.Stations, sessions)
 		if err != nil {
-			return fmt.Errorf("writing: %w", err)
+			return fmt.Errorf("failed to write sessions: %w", err)
 		}
+		// new slices, the whole solution is still followed by -watch
+		waypoints = sessions[0].Stops(waypoints)
+		targets = sessions[0].Stops(targets)
 	}
 
-	// End can go nowhere.
-	for range distanceWithFakeStartAndEnd - 1 {
-		_, err = w.WriteString("-1 ")
-		if err != nil {
-			return fmt.Errorf("writing: %w", err)
+	if opts.navigate != nil {
+		stations := solution.Stations
+		if stations != nil {
+			stations = stations[:min(len(stations), len(targets))]
 		}
-	}
-	_, err = w.WriteString("0\n")
-	if err != nil {
-		return fmt.Errorf("writing: %w", err)
+		return navigate(opts.session, g, problem.Full, targets, stations, opts.constraints, lkh, opts.navigate)
 	}
 
-	_, err = w.WriteString("EOF\n")
+	token, _, err := opts.session.Token()
 	if err != nil {
-		return fmt.Errorf("writing: %w", err)
+		return err
 	}
-
-	err = w.Flush()
+	err = client.AddWaypoints(token, waypoints)
 	if err != nil {
-		return fmt.Errorf("flushing: %w", err)
+		return fmt.Errorf("failed to add waypoints to UI: %w", err)
 	}
 
+	if opts.watch != nil {
+		return follow(opts.session, g, problem.Full, solutionAsIds, opts.constraints, lkh, opts.watch)
+	}
 	return nil
 }
 
 func main() {
-	if err := run(); err != nil {
-		os.Stderr.WriteString(err.Error())
-		os.Exit(1)
+	var err error
+	switch {
+	case len(os.Args) > 1 && os.Args[1] == "visited":
+		err = runVisited(os.Args[2:])
+	case len(os.Args) > 1 && os.Args[1] == "courier":
+		err = runCourier(os.Args[2:])
+	case len(os.Args) > 1 && os.Args[1] == "trade":
+		err = runTrade(os.Args[2:])
+	default:
+		err = run()
 	}
-}
-
-// parseAlreadyVisitedSystems only output systems in the reachable set.
-func parseAlreadyVisitedSystems(g graph) (map[uint32]struct{}, error) {
-	nameToID := make(map[string]uint32)
-	var names []string
-	for id, node := range g.Nodes {
-		nameToID[node.Name] = id
-		names = append(names, node.Name)
-	}
-	matchName := "(" + strings.Join(names, "|") + ")"
-	r, err := regexp.Compile("Jumping from " + matchName + " to " + matchName)
 	if err != nil {
-		return nil, fmt.Errorf("compiling regexp: %w", err)
-	}
-
-	visited := make(map[uint32]struct{})
-
-	for _, logName := range flag.Args() {
-		fmt.Println("parsing:", logName)
-		if err := func() error {
-			f, err := os.Open(logName)
-			if err != nil {
-				return fmt.Errorf("opening log file: %w", err)
-			}
-			defer f.Close()
-
-			scanner := bufio.NewScanner(bufio.NewReaderSize(f, 1024*1024*32))
-			for scanner.Scan() {
-				matches := r.FindSubmatch(scanner.Bytes())
-				if matches == nil {
-					continue
-				}
-
-				from, ok := nameToID[string(matches[1])]
-				if !ok {
-					return fmt.Errorf("unknown system: %s", matches[1])
-				}
-				if _, ok := g.Reachable[from]; ok {
-					visited[from] = struct{}{}
-				}
-
-				to, ok := nameToID[string(matches[2])]
-				if !ok {
-					return fmt.Errorf("unknown system: %s", matches[2])
-				}
-				if _, ok := g.Reachable[to]; ok {
-					visited[to] = struct{}{}
-				}
-			}
-			return nil
-		}(); err != nil {
-			return nil, err
-		}
+		os.Stderr.WriteString(err.Error())
+		os.Exit(1)
 	}
-
-	return visited, nil
 }
 
-func loadSolution(filepath string, isSop bool) (solution []uint, err error) {
-	f, err := os.Open(filepath)
+// writeSessions prints the route split into sessions and saves it to sessions.txt.
+func writeSessions(g universe.Graph, solution []uint32, stations []uint32, sessions []route.Session) error {
+	output, err := os.Create("sessions.txt")
 	if err != nil {
-		return nil, fmt.Errorf("opening solution file: %w", err)
+		return fmt.Errorf("creating sessions file: %w", err)
 	}
-	defer f.Close()
+	defer output.Close()
 
-	scanner := bufio.NewScanner(bufio.NewReaderSize(f, 1024*1024*32))
-	for scanner.Scan() {
-		if string(scanner.Bytes()) == "TOUR_SECTION" {
-			goto parse
+	w := bufio.NewWriterSize(io.MultiWriter(output, os.Stdout), 1024*1024*32)
+	for i, s := range sessions {
+		fmt.Fprintf(w, "Session %d: %d targets, %d jumps", i+1, len(s.Targets), s.Jumps)
+		if s.Seconds != 0 {
+			fmt.Fprintf(w, ", %s", time.Duration(s.Seconds)*time.Second)
 		}
-	}
-	return nil, fmt.Errorf("TOUR_SECTION not found")
-parse:
-	for scanner.Scan() {
-		b := scanner.Bytes()
-		if string(b) == "-1" {
-			break
+		if s.Over {
+			w.WriteString(", over the limit")
 		}
-
-		matrixIndex, err := strconv.ParseUint(string(b), 10, 32)
-		if err != nil {
-			return nil, fmt.Errorf("parsing matrix index: %w", err)
+		w.WriteByte('\n')
+		for _, t := range s.Targets {
+			w.WriteByte('\t')
+			if stations != nil {
+				st, _ := g.Nodes[solution[t]].Station(stations[t])
+				w.WriteString(st.Name)
+			} else {
+				w.WriteString(g.Nodes[solution[t]].Name)
+			}
+			w.WriteByte('\n')
 		}
-		matrixIndex-- // LKH is one-indexed
-		if isSop {
-			matrixIndex-- // SOP has a fake start node
+		if s.Detour > 0 {
+			fmt.Fprintf(w, "\tdock in %s, %d jumps away\n", g.Nodes[s.End].Name, s.Detour)
 		}
-		solution = append(solution, uint(matrixIndex))
 	}
-
-	if isSop {
-		// Remove the fake start and end nodes.
-		solution = solution[1 : len(solution)-1]
+	err = w.Flush()
+	if err != nil {
+		return fmt.Errorf("flushing: %w", err)
 	}
-
-	return solution, nil
+	return nil
 }
 
-func writeOutput(g graph, solution []uint32) error {
+// writeOutput lists the systems of the solution, stations is nil or the station to dock at in each.
+func writeOutput(g universe.Graph, solution []uint32, stations []uint32) error {
 	output, err := os.Create("output.txt")
 	if err != nil {
 		return fmt.Errorf("creating output file: %w", err)
@@ -514,8 +530,13 @@ func writeOutput(g graph, solution []uint32) error {
 	defer output.Close()
 
 	w := bufio.NewWriterSize(output, 1024*1024*32)
-	for _, systemID := range solution {
-		w.WriteString(g.Nodes[systemID].Name)
+	for i, systemID := range solution {
+		if stations != nil {
+			st, _ := g.Nodes[systemID].Station(stations[i])
+			w.WriteString(st.Name)
+		} else {
+			w.WriteString(g.Nodes[systemID].Name)
+		}
 		w.WriteByte('\n')
 	}
 	err = w.Flush()
@@ -527,3 +548,30 @@ func writeOutput(g graph, solution []uint32) error {
 
 	return nil
 }
+
+// printReport shows what the tour costs, in total and region by region.
+func printReport(g universe.Graph, r route.Report) {
+	fmt.Printf("%d targets, %d jumps, cost %d\n", r.Targets, r.Jumps, r.Cost)
+	if r.Longest.From != 0 {
+		fmt.Printf("longest leg: %s -> %s, %d jumps, cost %d\n", g.Nodes[r.Longest.From].Name, g.Nodes[r.Longest.To].Name, r.Longest.Jumps, r.Longest.Cost)
+	}
+	for _, region := range r.Regions {
+		fmt.Printf("  %s: %d targets, %d jumps, cost %d\n", region.Region, region.Targets, region.Jumps, region.Cost)
+	}
+}
+
+// printGap compares cost to the lower bounds, a small gap means more LKH RUNS won't help much.
+func printGap(b route.Bounds, cost uint64) {
+	fmt.Printf("lower bound %d (trivial %d, Held-Karp %d), ", b.Best(), b.Trivial, b.HeldKarp)
+	gap := b.Gap(cost)
+	if gap == 0 {
+		fmt.Println("the tour is optimal")
+		return
+	}
+	if math.IsInf(gap, 1) {
+		// every target has a free way in and out, like stations pairing up in systems
+		fmt.Println("nothing to compare the tour to")
+		return
+	}
+	fmt.Printf("the tour is at most %.1f%% longer than optimal\n", gap*100)
+}
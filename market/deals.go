@@ -0,0 +1,206 @@
+package market
+
+import (
+	"cmp"
+	"slices"
+
+	"eve-tour/courier"
+	"eve-tour/esi"
+	"eve-tour/universe"
+)
+
+// Fees are fractions of the sale price, like 0.036 for a 3.6% sales tax.
+type Fees struct {
+	SalesTax  float64
+	BrokerFee float64
+	// List sells by undercutting the destination's cheapest sell order instead of filling buy orders, paying the broker fee on top of the tax.
+	// How much the destination absorbs is unknown, we only sell as many units as that cheapest order has left.
+	List bool
+}
+
+// Deal buys units from the sell orders of one location and sells them at an other.
+type Deal struct {
+	Type                     uint32
+	From, To                 uint32
+	FromLocation, ToLocation uint64
+	Quantity                 int64
+	// Volume is in m³, Cost is the ISK spent buying and Profit what's left after selling and paying the fees.
+	Volume, Cost, Profit float64
+}
+
+// order is a market order placed in a reachable system, with what is left of it while deals are matched.
+type order struct {
+	esi.MarketOrder
+	system uint32
+	remain int64
+}
+
+// Candidates returns the types with a sell order cheaper than some buy order, the only ones [Deals] needs volumes of.
+// With fees.List every type sold in two places is one.
+func Candidates(orders []esi.MarketOrder, fees Fees) []uint32 {
+	lowest := make(map[uint32]float64)
+	highest := make(map[uint32]float64)
+	sellers := make(map[uint32]map[uint64]struct{})
+	for _, o := range orders {
+		if o.IsBuyOrder {
+			highest[o.TypeID] = max(highest[o.TypeID], o.Price)
+			continue
+		}
+		if p, ok := lowest[o.TypeID]; !ok || o.Price < p {
+			lowest[o.TypeID] = o.Price
+		}
+		if sellers[o.TypeID] == nil {
+			sellers[o.TypeID] = make(map[uint64]struct{})
+		}
+		sellers[o.TypeID][o.LocationID] = struct{}{}
+	}
+
+	var types []uint32
+	for _, t := range typesOf(orders) {
+		p, ok := lowest[t]
+		if ok && (highest[t]*(1-fees.SalesTax) > p || fees.List && len(sellers[t]) > 1) {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// Deals matches cheap sell orders with better paying buyers, best margins first, each order's units go to at most one deal.
+// Orders are placed by their system_id, or through the stations and structures of g for dumps without it.
+// A deal never needs more than limits' capacity and collateral, the wallet, so each fits the hold on its own.
+func Deals(g universe.Graph, orders []esi.MarketOrder, types map[uint32]esi.Type, fees Fees, limits courier.Limits) []Deal {
+	locations := courier.Locations(g)
+	sells := make(map[uint32][]*order)
+	buys := make(map[uint32][]*order)
+	for _, o := range orders {
+		system := o.SystemID
+		if system == 0 {
+			system = locations[o.LocationID]
+		}
+		if _, ok := g.IdsToMatrixIndexes[system]; !ok || o.VolumeRemain <= 0 {
+			continue
+		}
+		if _, ok := types[o.TypeID]; !ok {
+			continue
+		}
+		placed := &order{MarketOrder: o, system: system, remain: o.VolumeRemain}
+		if o.IsBuyOrder {
+			buys[o.TypeID] = append(buys[o.TypeID], placed)
+		} else {
+			sells[o.TypeID] = append(sells[o.TypeID], placed)
+		}
+	}
+
+	var deals []Deal
+	for _, t := range typesOf(orders) {
+		if len(sells[t]) == 0 {
+			continue
+		}
+		targets, fee := buys[t], fees.SalesTax
+		if fees.List {
+			targets, fee = listings(sells[t]), fees.SalesTax+fees.BrokerFee
+		}
+		deals = append(deals, match(t, unitVolume(types[t]), sells[t], targets, fee, limits)...)
+	}
+	return deals
+}
+
+// listings are the sales we can make by undercutting the cheapest sell order of each location, as if they were buy orders.
+func listings(sells []*order) []*order {
+	cheapest := make(map[uint64]*order)
+	for _, o := range sells {
+		if c, ok := cheapest[o.LocationID]; !ok || o.Price < c.Price {
+			cheapest[o.LocationID] = o
+		}
+	}
+	var listed []*order
+	for _, o := range cheapest {
+		l := *o
+		l.IsBuyOrder = true
+		l.Price = o.Price - 0.01
+		l.MinVolume = 1
+		listed = append(listed, &l)
+	}
+	return listed
+}
+
+// match crosses the sell orders of one type with the buy orders, the cheapest sell with the best paying buy until none is profitable.
+func match(t uint32, volume float64, sells, buys []*order, fee float64, limits courier.Limits) []Deal {
+	sells, buys = slices.Clone(sells), slices.Clone(buys)
+	slices.SortFunc(sells, func(a, b *order) int {
+		return cmp.Or(cmp.Compare(a.Price, b.Price), cmp.Compare(a.OrderID, b.OrderID))
+	})
+	slices.SortFunc(buys, func(a, b *order) int {
+		return cmp.Or(cmp.Compare(b.Price, a.Price), cmp.Compare(a.OrderID, b.OrderID))
+	})
+
+	type pair struct{ from, to uint64 }
+	byPair := make(map[pair]int)
+	var deals []Deal
+	for len(sells) > 0 && len(buys) > 0 {
+		s, b := sells[0], buys[0]
+		unitProfit := b.Price*(1-fee) - s.Price
+		if unitProfit <= 0 {
+			break
+		}
+		if b.OrderID == s.OrderID {
+			sells = sells[1:] // can't undercut ourselves
+			continue
+		}
+		q := min(s.remain, b.remain)
+		if q < b.MinVolume {
+			buys = buys[1:] // what's left of the cheapest seller doesn't meet the minimum, try the next buyer
+			continue
+		}
+
+		p := pair{s.LocationID, b.LocationID}
+		i, ok := byPair[p]
+		if !ok {
+			i = len(deals)
+			byPair[p] = i
+			deals = append(deals, Deal{Type: t, From: s.system, To: b.system, FromLocation: s.LocationID, ToLocation: b.LocationID})
+		}
+		d := &deals[i]
+		fits := q
+		if limits.Capacity != 0 && volume > 0 {
+			fits = min(fits, int64((limits.Capacity-d.Volume)/volume))
+		}
+		if limits.Collateral != 0 {
+			fits = min(fits, int64((limits.Collateral-d.Cost)/s.Price))
+		}
+		if fits > 0 {
+			d.Quantity += fits
+			d.Volume += float64(fits) * volume
+			d.Cost += float64(fits) * s.Price
+			d.Profit += float64(fits) * unitProfit
+		}
+		// units that don't fit are left to the market, consume them anyway so the loop moves on
+		s.remain -= q
+		b.remain -= q
+		if s.remain == 0 {
+			sells = sells[1:]
+		}
+		if b.remain == 0 {
+			buys = buys[1:]
+		}
+	}
+	return slices.DeleteFunc(deals, func(d Deal) bool { return d.Quantity == 0 })
+}
+
+// Contracts turns deals into courier contracts for [courier.Solve], the cost of a deal is the collateral at stake while it's carried.
+func Contracts(deals []Deal) []courier.Contract {
+	contracts := make([]courier.Contract, len(deals))
+	for i, d := range deals {
+		contracts[i] = courier.Contract{
+			ID:           uint32(i),
+			From:         d.From,
+			To:           d.To,
+			FromLocation: d.FromLocation,
+			ToLocation:   d.ToLocation,
+			Volume:       d.Volume,
+			Reward:       d.Profit,
+			Collateral:   d.Cost,
+		}
+	}
+	return contracts
+}
@@ -0,0 +1,151 @@
+package market
+
+import (
+	"math"
+	"testing"
+
+	"eve-tour/courier"
+	"eve-tour/esi"
+	"eve-tour/universe"
+)
+
+const tritanium = 34
+
+// fixture has three systems, the orders of the last station only name their location.
+func fixture() (universe.Graph, []esi.MarketOrder) {
+	g := universe.Graph{
+		Nodes: map[uint32]universe.System{
+			1: {Stations: []uint32{60000001}},
+			2: {Stations: []uint32{60000002}},
+			3: {Stations: []uint32{60000003, 60000004}},
+		},
+		IdsToMatrixIndexes: map[uint32]uint{1: 0, 2: 1, 3: 2},
+	}
+	orders := []esi.MarketOrder{
+		{OrderID: 1, TypeID: tritanium, LocationID: 60000001, SystemID: 1, Price: 5, VolumeRemain: 100},
+		{OrderID: 2, TypeID: tritanium, LocationID: 60000002, SystemID: 2, Price: 6, VolumeRemain: 50},
+		{OrderID: 3, TypeID: tritanium, LocationID: 60000003, SystemID: 3, IsBuyOrder: true, Price: 10, VolumeRemain: 120, MinVolume: 1},
+		{OrderID: 4, TypeID: tritanium, LocationID: 60000004, IsBuyOrder: true, Price: 8, VolumeRemain: 100, MinVolume: 1},
+		// nobody sells it cheaper than it's bought
+		{OrderID: 5, TypeID: 35, LocationID: 60000001, SystemID: 1, Price: 20, VolumeRemain: 10},
+		{OrderID: 6, TypeID: 35, LocationID: 60000003, SystemID: 3, IsBuyOrder: true, Price: 15, VolumeRemain: 10, MinVolume: 1},
+	}
+	return g, orders
+}
+
+var types = map[uint32]esi.Type{
+	tritanium: {Name: "Tritanium", Volume: 0.01},
+	35:        {Name: "Pyerite", Volume: 0.01},
+}
+
+func TestDeals(t *testing.T) {
+	tests := []struct {
+		name   string
+		edit   func(orders []esi.MarketOrder)
+		fees   Fees
+		limits courier.Limits
+		want   []Deal
+	}{
+		{
+			// the cheapest sell fills the best buy, what the buy has left goes to the next sell, whose rest goes to the second buy
+			name: "matching",
+			fees: Fees{SalesTax: 0.1},
+			want: []Deal{
+				{From: 1, To: 3, FromLocation: 60000001, ToLocation: 60000003, Quantity: 100, Volume: 1, Cost: 500, Profit: 400},
+				{From: 2, To: 3, FromLocation: 60000002, ToLocation: 60000003, Quantity: 20, Volume: 0.2, Cost: 120, Profit: 60},
+				{From: 2, To: 3, FromLocation: 60000002, ToLocation: 60000004, Quantity: 30, Volume: 0.3, Cost: 180, Profit: 36},
+			},
+		},
+		{
+			name: "tax eats the margin",
+			fees: Fees{SalesTax: 0.3},
+			// 10 * 0.7 - 5, 10 * 0.7 - 6 and 8 * 0.7 is less than 6
+			want: []Deal{
+				{From: 1, To: 3, FromLocation: 60000001, ToLocation: 60000003, Quantity: 100, Volume: 1, Cost: 500, Profit: 200},
+				{From: 2, To: 3, FromLocation: 60000002, ToLocation: 60000003, Quantity: 20, Volume: 0.2, Cost: 120, Profit: 20},
+			},
+		},
+		{
+			name: "minimum volume",
+			edit: func(orders []esi.MarketOrder) { orders[3].MinVolume = 50 },
+			fees: Fees{SalesTax: 0.1},
+			want: []Deal{
+				{From: 1, To: 3, FromLocation: 60000001, ToLocation: 60000003, Quantity: 100, Volume: 1, Cost: 500, Profit: 400},
+				{From: 2, To: 3, FromLocation: 60000002, ToLocation: 60000003, Quantity: 20, Volume: 0.2, Cost: 120, Profit: 60},
+			},
+		},
+		{
+			name:   "cargo capacity",
+			fees:   Fees{SalesTax: 0.1},
+			limits: courier.Limits{Capacity: 0.5},
+			want: []Deal{
+				{From: 1, To: 3, FromLocation: 60000001, ToLocation: 60000003, Quantity: 50, Volume: 0.5, Cost: 250, Profit: 200},
+				{From: 2, To: 3, FromLocation: 60000002, ToLocation: 60000003, Quantity: 20, Volume: 0.2, Cost: 120, Profit: 60},
+				{From: 2, To: 3, FromLocation: 60000002, ToLocation: 60000004, Quantity: 30, Volume: 0.3, Cost: 180, Profit: 36},
+			},
+		},
+		{
+			name:   "wallet",
+			fees:   Fees{SalesTax: 0.1},
+			limits: courier.Limits{Collateral: 150},
+			want: []Deal{
+				{From: 1, To: 3, FromLocation: 60000001, ToLocation: 60000003, Quantity: 30, Volume: 0.3, Cost: 150, Profit: 120},
+				{From: 2, To: 3, FromLocation: 60000002, ToLocation: 60000003, Quantity: 20, Volume: 0.2, Cost: 120, Profit: 60},
+				{From: 2, To: 3, FromLocation: 60000002, ToLocation: 60000004, Quantity: 25, Volume: 0.25, Cost: 150, Profit: 30},
+			},
+		},
+		{
+			// undercutting 6 at the second station, 5.99 * 0.85 - 5 a unit for as many as the 6 order has left
+			name: "listing",
+			fees: Fees{SalesTax: 0.1, BrokerFee: 0.05, List: true},
+			want: []Deal{
+				{From: 1, To: 2, FromLocation: 60000001, ToLocation: 60000002, Quantity: 50, Volume: 0.5, Cost: 250, Profit: 50 * (5.99*0.85 - 5)},
+			},
+		},
+		{
+			// a station's cheapest order is never undercut by itself
+			name: "listing own orders",
+			edit: func(orders []esi.MarketOrder) { orders[1].LocationID, orders[1].SystemID = 60000001, 1 },
+			fees: Fees{List: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, orders := fixture()
+			if tt.edit != nil {
+				tt.edit(orders)
+			}
+			got := Deals(g, orders, types, tt.fees, tt.limits)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got deals %+v, want %+v", got, tt.want)
+			}
+			for i, d := range got {
+				want := tt.want[i]
+				want.Type = tritanium
+				if !closeDeal(d, want) {
+					t.Errorf("deal %d = %+v, want %+v", i, d, want)
+				}
+			}
+		})
+	}
+}
+
+func closeDeal(a, b Deal) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-6 }
+	return a.Type == b.Type && a.From == b.From && a.To == b.To && a.FromLocation == b.FromLocation && a.ToLocation == b.ToLocation &&
+		a.Quantity == b.Quantity && near(a.Volume, b.Volume) && near(a.Cost, b.Cost) && near(a.Profit, b.Profit)
+}
+
+func TestMatchSkipsOwnListing(t *testing.T) {
+	own := &order{MarketOrder: esi.MarketOrder{OrderID: 1, LocationID: 60000001, Price: 5}, system: 1, remain: 10}
+	other := &order{MarketOrder: esi.MarketOrder{OrderID: 2, LocationID: 60000002, Price: 5.5}, system: 2, remain: 10}
+	// listings undercut, but even one above its own order isn't matched with it
+	listed := *own
+	listed.IsBuyOrder, listed.Price, listed.MinVolume = true, 6, 1
+
+	got := match(tritanium, 0.01, []*order{own, other}, []*order{&listed}, 0, courier.Limits{})
+	want := Deal{Type: tritanium, From: 2, To: 1, FromLocation: 60000002, ToLocation: 60000001, Quantity: 10, Volume: 0.1, Cost: 55, Profit: 5}
+	if len(got) != 1 || !closeDeal(got[0], want) {
+		t.Errorf("got deals %+v, want %+v", got, want)
+	}
+}
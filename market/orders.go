@@ -0,0 +1,118 @@
+// Package market finds trades between the stations of regional markets and hands them to the courier planner.
+package market
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+
+	"eve-tour/esi"
+)
+
+// TypesFile caches item volumes, they almost never change.
+const TypesFile = "types.json"
+
+// ReadOrders reads a dump of market orders in ESI's format, a JSON array like one page of /markets/{region}/orders or one order per line.
+func ReadOrders(path string) ([]esi.MarketOrder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening orders: %w", err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReaderSize(f, 1024*1024*32)
+	d := json.NewDecoder(br)
+	var orders []esi.MarketOrder
+	for {
+		// arrays and lone objects can follow each other, pages appended to the same file decode fine
+		var raw json.RawMessage
+		err = d.Decode(&raw)
+		if err == io.EOF {
+			return orders, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding orders: %w", err)
+		}
+		if len(raw) > 0 && raw[0] == '[' {
+			var page []esi.MarketOrder
+			err = json.Unmarshal(raw, &page)
+			orders = append(orders, page...)
+		} else {
+			var o esi.MarketOrder
+			err = json.Unmarshal(raw, &o)
+			orders = append(orders, o)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding orders: %w", err)
+		}
+	}
+}
+
+// LoadTypes reads the item types cache, it is empty if there is none.
+func LoadTypes(fileName string) (map[uint32]esi.Type, error) {
+	types := make(map[uint32]esi.Type)
+	b, err := os.ReadFile(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return types, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading types: %w", err)
+	}
+	err = json.Unmarshal(b, &types)
+	if err != nil {
+		return nil, fmt.Errorf("decoding types: %w", err)
+	}
+	return types, nil
+}
+
+func SaveTypes(fileName string, types map[uint32]esi.Type) error {
+	b, err := json.Marshal(types)
+	if err != nil {
+		return fmt.Errorf("encoding types: %w", err)
+	}
+	err = os.WriteFile(fileName, b, 0o644)
+	if err != nil {
+		return fmt.Errorf("writing types: %w", err)
+	}
+	return nil
+}
+
+// FetchTypes adds the missing ones of ids to types, it reports whether it fetched anything.
+func FetchTypes(client *esi.Client, types map[uint32]esi.Type, ids []uint32) (bool, error) {
+	var fetched bool
+	for _, id := range ids {
+		if _, ok := types[id]; ok {
+			continue
+		}
+		t, err := client.Type(id)
+		if err != nil {
+			return fetched, fmt.Errorf("fetching type %d: %w", id, err)
+		}
+		types[id] = t
+		fetched = true
+	}
+	return fetched, nil
+}
+
+// unitVolume is what one unit takes in the hold, ships travel packaged.
+func unitVolume(t esi.Type) float64 {
+	if t.PackagedVolume > 0 {
+		return t.PackagedVolume
+	}
+	return t.Volume
+}
+
+// typesOf lists the types orders trade, sorted.
+func typesOf(orders []esi.MarketOrder) []uint32 {
+	var ids []uint32
+	for _, o := range orders {
+		ids = append(ids, o.TypeID)
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"time"
+
+	"eve-tour/distance"
+	"eve-tour/esi"
+	"eve-tour/route"
+	"eve-tour/universe"
+	"eve-tour/visited"
+)
+
+// navigation configures -navigate.
+type navigation struct {
+	// waypoints is how many waypoints are kept set in game.
+	waypoints int
+	interval  time.Duration
+	store     *visited.Store
+}
+
+// navigator keeps the next few waypoints of a route set in game while the pilot flies it.
+type navigator[T distance.Weight] struct {
+	// ctx stops replanning when navigation is interrupted.
+	ctx         context.Context
+	session     *esi.Session
+	g           universe.Graph
+	full        distance.Matrix[T]
+	constraints []route.Constraint
+	lkh         route.Solver
+	n           *navigation
+
+	// remaining are the targets left, stations is nil or the station to dock at for each.
+	// A system can come up more than once, for two stations in it.
+	remaining []uint32
+	stations  []uint32
+	// set are the waypoints currently in game, the first ones of remaining.
+	set  []uint32
+	last uint32
+	// lost is set while the pilot is somewhere the route can't be planned from, off the crawled graph.
+	lost bool
+}
+
+// waypoint returns the autopilot destination of the ith remaining target.
+func (nav *navigator[T]) waypoint(i int) uint32 {
+	if nav.stations != nil {
+		return nav.stations[i]
+	}
+	return nav.remaining[i]
+}
+
+// sync makes the game's waypoints the first ones of remaining.
+// If the game only lost waypoints from the front, as the autopilot does when reaching them, the missing ones are appended, otherwise the list is set again.
+func (nav *navigator[T]) sync(arrived []uint32) error {
+	token, _, err := nav.session.Token()
+	if err != nil {
+		return err
+	}
+	want := nav.remaining[:min(nav.n.waypoints, len(nav.remaining))]
+	reached := func(id uint32) bool { return slices.Contains(arrived, id) }
+	kept := nav.set
+	for len(kept) > 0 && reached(kept[0]) {
+		kept = kept[1:]
+	}
+	if slices.ContainsFunc(kept, reached) || len(kept) > len(want) || !slices.Equal(kept, want[:len(kept)]) {
+		kept = nil // reached out of order or planned again, start over
+	}
+
+	for i := len(kept); i < len(want); i++ {
+		err = nav.session.Client.AddWaypoint(token, nav.waypoint(i), i == 0)
+		if err != nil {
+			return fmt.Errorf("failed to add waypoint to UI: %w", err)
+		}
+	}
+	nav.set = slices.Clone(want)
+	return nil
+}
+
+// replan solves what's left from current again.
+// If current isn't on the graph, a wormhole or a clone in a region that wasn't crawled, it waits for the next system instead.
+func (nav *navigator[T]) replan(current uint32) error {
+	problem := route.NewWeightedProblem(nav.g, nav.full, nav.remaining)
+	problem.Stations = nav.stations
+	firstHopCosts, err := problem.FirstHopCosts(nav.g, current)
+	if err != nil {
+		fmt.Println("can't plan from here, waiting for the next system:", err)
+		nav.lost = true
+		return nil
+	}
+	nav.lost = false
+	// warnings were given on the first plan
+	precedences, err := problem.Precedences(nav.g, nav.constraints, nil)
+	if err != nil {
+		return err
+	}
+	initial, _ := problem.Seed(nav.g, problem.LastTour(), firstHopCosts)
+	order, err := route.SolveSOPOrder(nav.ctx, nav.lkh, problem, firstHopCosts, precedences, initial)
+	if err != nil {
+		return fmt.Errorf("failed to solve SOP: %w", err)
+	}
+	problem = problem.Narrow(order)
+	nav.remaining, nav.stations = problem.Systems, problem.Stations
+	err = writeOutput(nav.g, nav.remaining, nav.stations)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nav.sync(nil)
+}
+
+// move handles the character showing up in system, done is true once every target is visited.
+func (nav *navigator[T]) move(system uint32) (done bool, err error) {
+	from := nav.last
+	nav.last = system
+	next := nav.remaining[0]
+	var arrived []uint32
+	left := 0
+	for i, id := range nav.remaining {
+		if _, ok := nav.n.store.Systems[id]; ok {
+			arrived = append(arrived, id)
+			continue
+		}
+		nav.remaining[left] = id
+		if nav.stations != nil {
+			nav.stations[left] = nav.stations[i]
+		}
+		left++
+	}
+	nav.remaining = nav.remaining[:left]
+	if nav.stations != nil {
+		nav.stations = nav.stations[:left]
+	}
+	fmt.Println("in", systemName(nav.g, system)+",", len(nav.remaining), "systems left")
+	if len(nav.remaining) == 0 {
+		return true, nil
+	}
+	if _, ok := nav.g.IdsToMatrixIndexes[system]; !ok || nav.lost {
+		// off the graph, or back on it from who knows where
+		return false, nav.replan(system)
+	}
+
+	if nav.remaining[0] == next && from != 0 {
+		before := nav.full.At(nav.g.IdsToMatrixIndexes[from], nav.g.IdsToMatrixIndexes[next])
+		after := nav.full.At(nav.g.IdsToMatrixIndexes[system], nav.g.IdsToMatrixIndexes[next])
+		if after >= before {
+			// a wrong turn, or a pod waking up in its clone
+			fmt.Println("off route, planning again from", nav.g.Nodes[system].Name)
+			return false, nav.replan(system)
+		}
+	}
+	if len(arrived) == 0 {
+		return false, nil
+	}
+	return false, nav.sync(arrived)
+}
+
+// systemName falls back to the ID for systems that weren't crawled, wormholes for one.
+func systemName(g universe.Graph, id uint32) string {
+	if name := g.Nodes[id].Name; name != "" {
+		return name
+	}
+	return fmt.Sprint("system ", id)
+}
+
+// navigate feeds the route to the autopilot a few waypoints at a time, polling the character's location through ESI until the tour is done or interrupted.
+// remaining are the systems left to visit, stations is nil or the station of each. Leaving the route solves the rest again with lkh.
+func navigate[T distance.Weight](session *esi.Session, g universe.Graph, full distance.Matrix[T], remaining, stations []uint32, constraints []route.Constraint, lkh route.Solver, n *navigation) error {
+	if len(remaining) == 0 {
+		return nil
+	}
+	nav := &navigator[T]{
+		ctx:         context.Background(),
+		session:     session,
+		g:           g,
+		full:        full,
+		constraints: constraints,
+		lkh:         lkh,
+		n:           n,
+		remaining:   slices.Clone(remaining),
+		stations:    slices.Clone(stations),
+	}
+	err := nav.sync(nil)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	nav.ctx = ctx
+	fmt.Println("navigating, press Ctrl+C to stop")
+
+	var moveErr error
+	err = n.store.Track(ctx, session, n.interval, logger, func(system uint32) {
+		done, err := nav.move(system)
+		if err != nil || done {
+			moveErr = err
+			cancel()
+		}
+		if done {
+			fmt.Println("tour complete!")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return moveErr
+}
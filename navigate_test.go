@@ -0,0 +1,89 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"eve-tour/distance"
+	"eve-tour/esi"
+	"eve-tour/esitest"
+	"eve-tour/universe"
+	"eve-tour/visited"
+)
+
+func TestNavigatorWaitsOffGraph(t *testing.T) {
+	const jita, perimeter, wormhole = 30000142, 30000144, 31000005
+	g := universe.Graph{
+		Nodes:              map[uint32]universe.System{jita: {Name: "Jita"}, perimeter: {Name: "Perimeter"}},
+		IdsToMatrixIndexes: map[uint32]uint{jita: 0, perimeter: 1},
+		MatrixIndexesToIds: []uint32{jita, perimeter},
+	}
+	full := distance.New[uint8](2)
+	full.Set(0, 1, 1)
+	full.Set(1, 0, 1)
+	nav := &navigator[uint8]{
+		g:         g,
+		full:      full,
+		n:         &navigation{waypoints: 10, store: &visited.Store{}},
+		remaining: []uint32{perimeter},
+		set:       []uint32{perimeter},
+		last:      jita,
+	}
+
+	done, err := nav.move(wormhole)
+	if err != nil || done {
+		t.Fatalf("moving off the graph: done %v, %v", done, err)
+	}
+	if !nav.lost {
+		t.Error("not waiting to get back on the graph")
+	}
+	if !slices.Equal(nav.remaining, []uint32{perimeter}) || !slices.Equal(nav.set, []uint32{perimeter}) {
+		t.Errorf("route changed to %v, waypoints to %v", nav.remaining, nav.set)
+	}
+}
+
+func TestNavigatorStationsInOneSystem(t *testing.T) {
+	client, srv := esitest.NewClient(t)
+	g := universe.Graph{
+		Nodes:              map[uint32]universe.System{esitest.Jita: {Name: "Jita"}, esitest.Perimeter: {Name: "Perimeter"}},
+		IdsToMatrixIndexes: map[uint32]uint{esitest.Jita: 0, esitest.Perimeter: 1},
+		MatrixIndexesToIds: []uint32{esitest.Jita, esitest.Perimeter},
+	}
+	full := distance.New[uint8](2)
+	full.Set(0, 1, 1)
+	full.Set(1, 0, 1)
+	store := &visited.Store{Systems: make(map[uint32]visited.Visit)}
+	nav := &navigator[uint8]{
+		session:   &esi.Session{Client: client},
+		g:         g,
+		full:      full,
+		n:         &navigation{waypoints: 10, store: store},
+		remaining: []uint32{esitest.Jita, esitest.Jita, esitest.Perimeter},
+		stations:  []uint32{60003760, 60003466, 60000361},
+	}
+
+	err := nav.sync(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []esitest.Waypoint{
+		{DestinationID: 60003760, ClearOtherWaypoints: true},
+		{DestinationID: 60003466},
+		{DestinationID: 60000361},
+	}
+	if got := srv.Waypoints(); !slices.Equal(got, want) {
+		t.Errorf("set waypoints %+v, want %+v", got, want)
+	}
+
+	store.Systems[esitest.Jita] = visited.Visit{}
+	done, err := nav.move(esitest.Jita)
+	if err != nil || done {
+		t.Fatalf("docking in Jita: done %v, %v", done, err)
+	}
+	if !slices.Equal(nav.remaining, []uint32{esitest.Perimeter}) || !slices.Equal(nav.stations, []uint32{60000361}) {
+		t.Errorf("left %v at stations %v, want Perimeter's", nav.remaining, nav.stations)
+	}
+	if got := srv.Waypoints(); len(got) != len(want) {
+		t.Errorf("waypoints set again after reaching the first ones: %+v", got[len(want):])
+	}
+}
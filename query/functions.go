@@ -0,0 +1,148 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"eve-tour/universe"
+)
+
+// Functions are the calls queries can use, by name.
+var Functions = map[string]Function{
+	"jumps_from": {
+		Doc:  "jumps to the closest system matching a condition or to the named system, unreachable is infinite",
+		args: [][]kind{{boolKind, strKind}},
+		kind: numKind,
+		bind: bindJumpsFrom,
+	},
+	"has_service": {
+		Doc:  "an NPC station or a public structure offers the service",
+		args: [][]kind{{strKind}},
+		kind: boolKind,
+		bind: bindHasService(true),
+	},
+	"has_npc_service": {
+		Doc:  "an NPC station offers the service",
+		args: [][]kind{{strKind}},
+		kind: boolKind,
+		bind: bindHasService(false),
+	},
+	"has_owner": {
+		Doc:  "an NPC station is run by the corporation ID",
+		args: [][]kind{{numKind}},
+		kind: boolKind,
+		bind: bindHasOwner,
+	},
+}
+
+// serviceAliases maps friendlier names to ESI's service names, which are also accepted as is.
+var serviceAliases = map[string]string{
+	"reprocessing":  "reprocessing-plant",
+	"clone-bay":     "jump-clone-facility",
+	"jump-clone":    "jump-clone-facility",
+	"repair":        "repair-facilities",
+	"lp-store":      "loyalty-point-store",
+	"manufacturing": "factory",
+	"research":      "labratory", // sic, that's how ESI spells it
+	"laboratory":    "labratory",
+}
+
+// literalArg returns the value of a literal argument, functions needing a constant reject fields.
+func literalArg(x expr) (value, error) {
+	lit, ok := x.(*literal)
+	if !ok {
+		return value{}, fmt.Errorf("needs a constant, not a field")
+	}
+	return lit.v, nil
+}
+
+func bindHasService(withStructures bool) func(e *env, args []expr) (func(uint32) value, error) {
+	return func(e *env, args []expr) (func(uint32) value, error) {
+		v, err := literalArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		service := strings.ReplaceAll(strings.ReplaceAll(strings.ToLower(strings.TrimSpace(v.s)), " ", "-"), "_", "-")
+		if alias, ok := serviceAliases[service]; ok {
+			service = alias
+		}
+		return func(id uint32) value {
+			return value{b: e.g.Nodes[id].HasService(service, withStructures)}
+		}, nil
+	}
+}
+
+func bindHasOwner(e *env, args []expr) (func(uint32) value, error) {
+	v, err := literalArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	owner := uint32(v.n)
+	return func(id uint32) value {
+		for _, st := range e.g.Nodes[id].StationInfo {
+			if st.Owner == owner {
+				return value{b: true}
+			}
+		}
+		return value{b: false}
+	}, nil
+}
+
+func bindJumpsFrom(e *env, args []expr) (func(uint32) value, error) {
+	var sources []uint32
+	if args[0].kind() == strKind {
+		v, err := literalArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		id, ok := e.g.Lookup(v.s)
+		if !ok {
+			return nil, fmt.Errorf("unknown system %q", v.s)
+		}
+		sources = []uint32{id}
+	} else {
+		for id := range e.g.Nodes {
+			if args[0].eval(e, id).b {
+				sources = append(sources, id)
+			}
+		}
+		slices.Sort(sources)
+	}
+
+	jumps := bfs(e.g, sources)
+	return func(id uint32) value {
+		if d, ok := jumps[id]; ok {
+			return value{n: float64(d)}
+		}
+		return value{n: math.Inf(1)}
+	}, nil
+}
+
+// bfs returns the jump count from the closest source to every system reachable from one.
+func bfs(g universe.Graph, sources []uint32) map[uint32]int {
+	dist := make(map[uint32]int, len(g.Nodes))
+	queue := make([]uint32, 0, len(g.Nodes))
+	for _, s := range sources {
+		if _, ok := dist[s]; !ok {
+			dist[s] = 0
+			queue = append(queue, s)
+		}
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, next := range g.Edges[v] {
+			if _, ok := g.Nodes[next]; !ok {
+				continue
+			}
+			if _, ok := dist[next]; ok {
+				continue
+			}
+			dist[next] = dist[v] + 1
+			queue = append(queue, next)
+		}
+	}
+	return dist
+}
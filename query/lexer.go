@@ -0,0 +1,98 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SyntaxError points at the offending byte of the query, Pos is one-indexed.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return "query:" + strconv.Itoa(e.Pos) + ": " + e.Msg
+}
+
+type tokenKind uint8
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp    // = == != < <= > >=
+	tokPunct // ( ) ,
+)
+
+type token struct {
+	kind tokenKind
+	text string // strings are unquoted
+	num  float64
+	pos  int
+}
+
+func errorAt(pos int, format string, args ...any) error {
+	return &SyntaxError{Pos: pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(' || r == ')' || r == ',':
+			tokens = append(tokens, token{kind: tokPunct, text: string(r), pos: start})
+			i++
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			i++
+			if i < len(src) && src[i] == '=' {
+				i++
+			}
+			op := src[start:i]
+			if op == "!" {
+				return nil, errorAt(start, "unexpected !, did you mean != or not")
+			}
+			if op == "==" {
+				op = "="
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: start})
+		case r == '"' || r == '\'':
+			end := strings.IndexRune(src[i+1:], r)
+			if end < 0 {
+				return nil, errorAt(start, "unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokString, text: src[i+1 : i+1+end], pos: start})
+			i += end + 2
+		case r == '-' || r == '.' || unicode.IsDigit(r):
+			i++
+			for i < len(src) && (src[i] == '.' || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			n, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, errorAt(start, "invalid number %q", src[start:i])
+			}
+			tokens = append(tokens, token{kind: tokNumber, num: n, text: src[start:i], pos: start})
+		case r == '_' || unicode.IsLetter(r):
+			for i < len(src) {
+				r, size := utf8.DecodeRuneInString(src[i:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		default:
+			return nil, errorAt(start, "unexpected %q", r)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
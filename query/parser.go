@@ -0,0 +1,247 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// keyword consumes the next token if it is the given word.
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokIdent && strings.EqualFold(t.text, word) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) punct(s string) bool {
+	t := p.peek()
+	if t.kind == tokPunct && t.text == s {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.punct(s) {
+		t := p.peek()
+		if t.kind == tokEOF {
+			return errorAt(t.pos, "expected %q, got the end of the query", s)
+		}
+		return errorAt(t.pos, "expected %q, got %q", s, t.text)
+	}
+	return nil
+}
+
+func condition(x expr, pos int) error {
+	if err := unknownField(x, pos); err != nil {
+		return err
+	}
+	if x.kind() != boolKind {
+		return errorAt(pos, "expected a condition, got a %s", x.kind())
+	}
+	return nil
+}
+
+func (p *parser) or() (expr, error) {
+	return p.logical("or", p.and)
+}
+
+func (p *parser) and() (expr, error) {
+	return p.logical("and", p.unary)
+}
+
+func (p *parser) logical(word string, operand func() (expr, error)) (expr, error) {
+	pos := p.peek().pos
+	l, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if !p.keyword(word) {
+			return l, nil
+		}
+		if err := condition(l, pos); err != nil {
+			return nil, err
+		}
+		rpos := p.peek().pos
+		r, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if err := condition(r, rpos); err != nil {
+			return nil, err
+		}
+		l = &logical{and: word == "and", l: l, r: r}
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	pos := p.peek().pos
+	if p.keyword("not") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if err := condition(x, pos); err != nil {
+			return nil, err
+		}
+		return &not{x: x}, nil
+	}
+	if p.punct("(") {
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	}
+	return p.comparison()
+}
+
+// comparison parses a term optionally followed by an operator, a lone term of any kind is returned as is.
+func (p *parser) comparison() (expr, error) {
+	pos := p.peek().pos
+	l, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if t.kind == tokOp || t.kind == tokIdent && (strings.EqualFold(t.text, "in") || strings.EqualFold(t.text, "not")) {
+		if err := unknownField(l, pos); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case t.kind == tokOp:
+		p.next()
+		rpos := p.peek().pos
+		r, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		if l.kind() != r.kind() {
+			return nil, errorAt(rpos, "cannot compare a %s with a %s", l.kind(), r.kind())
+		}
+		if l.kind() != numKind && t.text != "=" && t.text != "!=" {
+			return nil, errorAt(t.pos, "%s only works on numbers", t.text)
+		}
+		return &compare{op: t.text, l: l, r: r}, nil
+	case p.keyword("in"):
+		return p.in(l, pos, false)
+	case p.keyword("not"):
+		if !p.keyword("in") {
+			return nil, errorAt(t.pos, "expected in after not")
+		}
+		return p.in(l, pos, true)
+	}
+	return l, nil
+}
+
+func (p *parser) in(x expr, pos int, negate bool) (expr, error) {
+	if x.kind() == boolKind {
+		return nil, errorAt(pos, "in needs a number or a string")
+	}
+	err := p.expect("(")
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]struct{})
+	for {
+		t := p.next()
+		switch {
+		case t.kind == tokNumber && x.kind() == numKind:
+			set[fmt.Sprint(t.num)] = struct{}{}
+		case (t.kind == tokString || t.kind == tokIdent) && x.kind() == strKind:
+			set[strings.ToLower(t.text)] = struct{}{}
+		case t.kind == tokEOF:
+			return nil, errorAt(t.pos, "unterminated list")
+		default:
+			return nil, errorAt(t.pos, "expected a %s in the list, got %q", x.kind(), t.text)
+		}
+		if p.punct(")") {
+			return &in{x: x, set: set, negate: negate}, nil
+		}
+		err = p.expect(",")
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) term() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return &literal{k: numKind, v: value{n: t.num}}, nil
+	case tokString:
+		return &literal{k: strKind, v: value{s: t.text}}, nil
+	case tokIdent:
+		name := strings.ToLower(t.text)
+		if fn, ok := Functions[name]; ok {
+			return p.call(name, fn, t.pos)
+		}
+		if f, ok := Fields[name]; ok {
+			return &fieldRef{f: f}, nil
+		}
+		switch name {
+		case "and", "or", "not", "in":
+			return nil, errorAt(t.pos, "unexpected %s", name)
+		}
+		return &literal{k: strKind, v: value{s: t.text}, bare: true}, nil
+	case tokEOF:
+		return nil, errorAt(t.pos, "unexpected end of the query")
+	}
+	return nil, errorAt(t.pos, "unexpected %q", t.text)
+}
+
+func (p *parser) call(name string, fn Function, pos int) (expr, error) {
+	err := p.expect("(")
+	if err != nil {
+		return nil, err
+	}
+	var args []expr
+	for !p.punct(")") {
+		if len(args) > 0 {
+			err = p.expect(",")
+			if err != nil {
+				return nil, err
+			}
+		}
+		apos := p.peek().pos
+		if p.peek().kind == tokEOF {
+			return nil, errorAt(apos, "unterminated call to %s", name)
+		}
+		a, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if len(args) < len(fn.args) && !fn.accepts(len(args), a.kind()) {
+			return nil, errorAt(apos, "%s takes a %s here, got a %s", name, fn.argDoc(len(args)), a.kind())
+		}
+		args = append(args, a)
+	}
+	if len(args) != len(fn.args) {
+		return nil, errorAt(pos, "%s takes %d arguments, got %d", name, len(fn.args), len(args))
+	}
+	return &call{name: name, fn: fn, args: args}, nil
+}
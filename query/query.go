@@ -0,0 +1,301 @@
+// Package query parses target selection expressions such as
+//
+//	region in ("The Forge", Lonetrek) and security >= 0.5 and jumps_from(highsec) <= 2
+//
+// Expressions combine comparisons with and, or, not and parentheses.
+// Numbers compare with = != < <= > >=, strings with = != and in (...), case-insensitively.
+// Fields are listed in [Fields] and functions in [Functions]. Bare words that aren't either are strings where a value is expected,
+// on the right of a comparison, in lists and as arguments, elsewhere they are unknown fields.
+package query
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"eve-tour/universe"
+)
+
+type kind uint8
+
+const (
+	boolKind kind = iota
+	numKind
+	strKind
+)
+
+func (k kind) String() string {
+	return [...]string{"boolean", "number", "string"}[k]
+}
+
+type value struct {
+	b bool
+	n float64
+	s string
+}
+
+// env is what expressions are evaluated against, functions cache their precomputed tables in it.
+type env struct {
+	g     universe.Graph
+	cache map[expr]any
+}
+
+type expr interface {
+	kind() kind
+	eval(e *env, id uint32) value
+}
+
+// Field is a property of a system usable in queries.
+type Field struct {
+	Doc  string
+	kind kind
+	get  func(id uint32, s universe.System) value
+}
+
+// Fields are the properties of a system queries can test, by name.
+var Fields = map[string]Field{
+	"name":   {"the system's name", strKind, func(_ uint32, s universe.System) value { return value{s: s.Name} }},
+	"region": {"the system's region", strKind, func(_ uint32, s universe.System) value { return value{s: s.Region} }},
+	"constellation": {"the system's constellation, empty in graphs crawled before constellations were recorded", strKind, func(_ uint32, s universe.System) value {
+		return value{s: s.Constellation}
+	}},
+	"id":         {"the system's ID", numKind, func(id uint32, _ universe.System) value { return value{n: float64(id)} }},
+	"security":   {"the true security status", numKind, func(_ uint32, s universe.System) value { return value{n: security(s)} }},
+	"stations":   {"the number of NPC stations", numKind, func(_ uint32, s universe.System) value { return value{n: float64(len(s.Stations))} }},
+	"structures": {"the number of public structures, needs -structures", numKind, func(_ uint32, s universe.System) value { return value{n: float64(len(s.Structures))} }},
+	"max_dockable": {"the largest ship volume in m³ an NPC station docks", numKind, func(_ uint32, s universe.System) value {
+		var v float64
+		for _, st := range s.StationInfo {
+			v = max(v, st.MaxDockableShipVolume)
+		}
+		return value{n: v}
+	}},
+	"highsec": {"security >= 0.5", boolKind, func(_ uint32, s universe.System) value { return value{b: security(s) >= 0.5} }},
+	"lowsec":  {"0 < security < 0.5", boolKind, func(_ uint32, s universe.System) value { return value{b: security(s) > 0 && security(s) < 0.5} }},
+	"nullsec": {"security <= 0", boolKind, func(_ uint32, s universe.System) value { return value{b: security(s) <= 0} }},
+}
+
+// security rounds away float32 noise so 0.5 compares as written.
+func security(s universe.System) float64 {
+	return float64(int64(float64(s.SecurityStatus)*1e6+0.5)) / 1e6
+}
+
+// Function is a call usable in queries.
+type Function struct {
+	Doc string
+	// args lists the kinds each argument may have.
+	args [][]kind
+	kind kind
+	// bind checks the arguments against the graph and returns the evaluator, arguments are already bound.
+	bind func(e *env, args []expr) (func(id uint32) value, error)
+}
+
+func (f Function) accepts(i int, k kind) bool {
+	return slices.Contains(f.args[i], k)
+}
+
+func (f Function) argDoc(i int) string {
+	var kinds []string
+	for _, k := range f.args[i] {
+		kinds = append(kinds, k.String())
+	}
+	return strings.Join(kinds, " or ")
+}
+
+// Query is a parsed expression, [Query.Bind] it to a graph to evaluate it.
+type Query struct {
+	src  string
+	root expr
+}
+
+func (q *Query) String() string {
+	return q.src
+}
+
+// Parse parses and type checks src, the whole expression must be a boolean.
+func Parse(src string) (*Query, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorAt(t.pos, "unexpected %q", t.text)
+	}
+	if err := unknownField(root, 0); err != nil {
+		return nil, err
+	}
+	if root.kind() != boolKind {
+		return nil, errorAt(0, "query is a %s, not a condition", root.kind())
+	}
+	return &Query{src: src, root: root}, nil
+}
+
+// Bind resolves the query against g and returns its predicate.
+// Functions precompute what they need over the whole graph here, errors are unknown systems and such.
+func (q *Query) Bind(g universe.Graph) (func(id uint32, s universe.System) bool, error) {
+	e := &env{g: g, cache: make(map[expr]any)}
+	err := bindAll(q.root, e)
+	if err != nil {
+		return nil, err
+	}
+	return func(id uint32, _ universe.System) bool {
+		return q.root.eval(e, id).b
+	}, nil
+}
+
+// bindAll binds every call of the tree, innermost first.
+func bindAll(x expr, e *env) error {
+	switch x := x.(type) {
+	case *logical:
+		for _, y := range []expr{x.l, x.r} {
+			if err := bindAll(y, e); err != nil {
+				return err
+			}
+		}
+	case *not:
+		return bindAll(x.x, e)
+	case *compare:
+		for _, y := range []expr{x.l, x.r} {
+			if err := bindAll(y, e); err != nil {
+				return err
+			}
+		}
+	case *in:
+		return bindAll(x.x, e)
+	case *call:
+		for _, a := range x.args {
+			if err := bindAll(a, e); err != nil {
+				return err
+			}
+		}
+		f, err := x.fn.bind(e, x.args)
+		if err != nil {
+			return fmt.Errorf("%s: %w", x.name, err)
+		}
+		e.cache[x] = f
+	}
+	return nil
+}
+
+type literal struct {
+	k kind
+	v value
+	// bare is set for strings written as bare words, they may be misspelled fields.
+	bare bool
+}
+
+// unknownField reports a bare word used where only a field makes sense, instead of testing it as a string.
+func unknownField(x expr, pos int) error {
+	if l, ok := x.(*literal); ok && l.bare {
+		return errorAt(pos, "unknown field %q, fields are %s", l.v.s, strings.Join(slices.Sorted(maps.Keys(Fields)), ", "))
+	}
+	return nil
+}
+
+func (l *literal) kind() kind                    { return l.k }
+func (l *literal) eval(*env, uint32) value       { return l.v }
+func (f *fieldRef) kind() kind                   { return f.f.kind }
+func (f *fieldRef) eval(e *env, id uint32) value { return f.f.get(id, e.g.Nodes[id]) }
+
+type fieldRef struct {
+	f Field
+}
+
+type logical struct {
+	and  bool
+	l, r expr
+}
+
+func (*logical) kind() kind { return boolKind }
+func (x *logical) eval(e *env, id uint32) value {
+	l := x.l.eval(e, id).b
+	if l != x.and {
+		return value{b: l}
+	}
+	return x.r.eval(e, id)
+}
+
+type not struct {
+	x expr
+}
+
+func (*not) kind() kind { return boolKind }
+func (x *not) eval(e *env, id uint32) value {
+	return value{b: !x.x.eval(e, id).b}
+}
+
+type compare struct {
+	op   string
+	l, r expr
+}
+
+func (*compare) kind() kind { return boolKind }
+func (x *compare) eval(e *env, id uint32) value {
+	l, r := x.l.eval(e, id), x.r.eval(e, id)
+	var c int
+	switch x.l.kind() {
+	case numKind:
+		switch {
+		case l.n < r.n:
+			c = -1
+		case l.n > r.n:
+			c = 1
+		}
+	case strKind:
+		if !strings.EqualFold(l.s, r.s) {
+			c = strings.Compare(strings.ToLower(l.s), strings.ToLower(r.s))
+		}
+	case boolKind:
+		if l.b != r.b {
+			c = 1
+		}
+	}
+	switch x.op {
+	case "=":
+		return value{b: c == 0}
+	case "!=":
+		return value{b: c != 0}
+	case "<":
+		return value{b: c < 0}
+	case "<=":
+		return value{b: c <= 0}
+	case ">":
+		return value{b: c > 0}
+	default:
+		return value{b: c >= 0}
+	}
+}
+
+type in struct {
+	x      expr
+	set    map[string]struct{} // lower-cased strings or formatted numbers
+	negate bool
+}
+
+func (*in) kind() kind { return boolKind }
+func (x *in) eval(e *env, id uint32) value {
+	v := x.x.eval(e, id)
+	key := strings.ToLower(v.s)
+	if x.x.kind() == numKind {
+		key = fmt.Sprint(v.n)
+	}
+	_, ok := x.set[key]
+	return value{b: ok != x.negate}
+}
+
+type call struct {
+	name string
+	fn   Function
+	args []expr
+}
+
+func (c *call) kind() kind { return c.fn.kind }
+func (c *call) eval(e *env, id uint32) value {
+	return e.cache[c].(func(uint32) value)(id)
+}
@@ -0,0 +1,96 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"eve-tour/universe"
+)
+
+func testGraph() universe.Graph {
+	return universe.Graph{Nodes: map[uint32]universe.System{
+		1: {Name: "Jita", Region: "The Forge", Constellation: "Kimotoro", SecurityStatus: 0.9},
+		2: {Name: "Perimeter", Region: "The Forge", Constellation: "Kimotoro", SecurityStatus: 1},
+		3: {Name: "Urlen", Region: "The Forge", Constellation: "Okkamon", SecurityStatus: 0.95},
+		4: {Name: "Uedama", Region: "The Citadel", Constellation: "Ihilakken", SecurityStatus: 0.5},
+	}}
+}
+
+func TestConstellation(t *testing.T) {
+	g := testGraph()
+	tests := []struct {
+		src  string
+		want []uint32
+	}{
+		{"constellation = Kimotoro", []uint32{1, 2}},
+		{"constellation = kimotoro and name != Jita", []uint32{2}},
+		{"constellation in (Okkamon, 'Ihilakken')", []uint32{3, 4}},
+		{"constellation not in (Kimotoro)", []uint32{3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			q, err := Parse(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			match, err := q.Bind(g)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []uint32
+			for id := uint32(1); id <= 4; id++ {
+				if match(id, g.Nodes[id]) {
+					got = append(got, id)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("matched %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("matched %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUnknownField(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+	}{
+		{"constelation = Kimotoro", 1},
+		{"security >= 0.5 and regoin in (Lonetrek)", 21},
+		{"regoin not in (Lonetrek)", 1},
+		{"hisec", 1},
+		{"not hisec", 1},
+		{"highsec or hisec", 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			var syntax *SyntaxError
+			if !errors.As(err, &syntax) {
+				t.Fatalf("got %v, want a syntax error", err)
+			}
+			if syntax.Pos != tt.pos || !strings.HasPrefix(syntax.Msg, "unknown field") {
+				t.Errorf("got %v, want an unknown field at %d", err, tt.pos)
+			}
+		})
+	}
+}
+
+func TestBareWordValues(t *testing.T) {
+	for _, src := range []string{
+		"region = Lonetrek",
+		"region in (Lonetrek, 'The Forge')",
+		"jumps_from(Jita) <= 2",
+		"has_service(market)",
+	} {
+		if _, err := Parse(src); err != nil {
+			t.Errorf("%s: %v", src, err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package route
+
+import (
+	"math"
+
+	"eve-tour/distance"
+)
+
+// Bounds are lower bounds on the cost of any path through every node of a problem, from the start if there is one.
+// Precedences are ignored, they can only make paths longer.
+type Bounds struct {
+	// Trivial counts the cheapest way into and out of every node, on jump counts it is at least the number of systems minus one.
+	Trivial uint64
+	// HeldKarp is the 1-tree bound improved by subgradient optimization, 0 if it wasn't computed.
+	HeldKarp uint64
+}
+
+// Best is the highest of the bounds.
+func (b Bounds) Best() uint64 {
+	return max(b.Trivial, b.HeldKarp)
+}
+
+// Gap is how much more than the best bound cost is, as a fraction of the bound. 0 proves cost optimal.
+func (b Bounds) Gap(cost uint64) float64 {
+	best := b.Best()
+	if cost <= best {
+		return 0
+	}
+	if best == 0 {
+		return math.Inf(1)
+	}
+	return float64(cost-best) / float64(best)
+}
+
+// pathCosts looks at p as a path from a start node, index 0, through the nodes of p, index n+1 for node n.
+// Costs are made symmetric by taking the cheapest direction, which can only lower the bound.
+type pathCosts[T distance.Weight] struct {
+	p             Problem[T]
+	firstHopCosts []T
+}
+
+func (c pathCosts[T]) size() int {
+	return len(c.p.Systems) + 1
+}
+
+func (c pathCosts[T]) at(i, j int) float64 {
+	if i == 0 || j == 0 {
+		if c.firstHopCosts == nil {
+			return 0
+		}
+		return float64(c.firstHopCosts[i+j-1])
+	}
+	return float64(min(c.p.Matrix.At(uint(i-1), uint(j-1)), c.p.Matrix.At(uint(j-1), uint(i-1))))
+}
+
+// boundWork caps the matrix lookups spent on the Held-Karp bound, about a second's worth.
+const boundWork = 200_000_000
+
+// LowerBounds bounds the cost of solving p from the start if firstHopCosts isn't nil.
+// The Held-Karp bound runs up to iterations subgradient steps, 0 skips it, upper is the cost of a known path to aim the steps with.
+// Each step is a spanning tree over the whole matrix, so big problems get fewer, though never less than 10.
+func (p Problem[T]) LowerBounds(firstHopCosts []T, iterations int, upper uint64) Bounds {
+	if len(p.Systems) == 0 {
+		return Bounds{}
+	}
+	c := pathCosts[T]{p: p, firstHopCosts: firstHopCosts}
+	b := Bounds{Trivial: trivialBound(c)}
+	if iterations > 0 {
+		n := c.size()
+		iterations = min(iterations, max(10, boundWork/(n*n)))
+		b.HeldKarp = heldKarp(c, iterations, upper)
+	}
+	return b
+}
+
+// trivialBound adds up the cheapest way into each node, only one of them is entered from the start,
+// and does the same with the cheapest way out, no node is left after the last one.
+func trivialBound[T distance.Weight](c pathCosts[T]) uint64 {
+	n := c.size()
+	var in, out, lastOut float64
+	startSaving, bestStart := math.Inf(-1), math.Inf(1)
+	for j := 1; j < n; j++ {
+		minIn, minOut := math.Inf(1), math.Inf(1)
+		for i := 1; i < n; i++ {
+			if i != j {
+				minIn = min(minIn, float64(c.p.Matrix.At(uint(i-1), uint(j-1))))
+				minOut = min(minOut, float64(c.p.Matrix.At(uint(j-1), uint(i-1))))
+			}
+		}
+		start := c.at(0, j)
+		bestStart = min(bestStart, start)
+		if n == 2 {
+			minIn, minOut = start, 0 // a lone target is only entered from the start
+		}
+		in += minIn
+		startSaving = max(startSaving, minIn-start)
+		out += minOut
+		lastOut = max(lastOut, minOut)
+	}
+	return uint64(math.Ceil(max(in-startSaving, out-lastOut+bestStart)))
+}
+
+// heldKarp maximizes the 1-tree bound over node penalties.
+// The path is closed into a tour by a free end node linked to the start and to any other node at no cost,
+// its 1-tree is then a spanning tree of the others plus the start and the node with the lowest penalty.
+func heldKarp[T distance.Weight](c pathCosts[T], iterations int, upper uint64) uint64 {
+	n := c.size()
+	pi := make([]float64, n)
+	degree := make([]int, n)
+	best := 0.0
+
+	// spanning tree with Prim's algorithm, the matrix is dense
+	inTree := make([]bool, n)
+	dist := make([]float64, n)
+	parent := make([]int, n)
+
+	step := 2.0
+	sinceImproved := 0
+	for it := 0; it < iterations; it++ {
+		clear(inTree)
+		clear(degree)
+		for v := range dist {
+			dist[v] = math.Inf(1)
+		}
+		dist[0] = 0
+		var tree float64
+		for range n {
+			u := -1
+			for v := range n {
+				if !inTree[v] && (u < 0 || dist[v] < dist[u]) {
+					u = v
+				}
+			}
+			inTree[u] = true
+			tree += dist[u]
+			if u != 0 {
+				degree[u]++
+				degree[parent[u]]++
+			}
+			for v := range n {
+				if inTree[v] {
+					continue
+				}
+				if d := c.at(u, v) + pi[u] + pi[v]; d < dist[v] {
+					dist[v], parent[v] = d, u
+				}
+			}
+		}
+
+		end := 1
+		for v := 2; v < n; v++ {
+			if pi[v] < pi[end] {
+				end = v
+			}
+		}
+		bound := tree + pi[0] + pi[end]
+		for _, x := range pi {
+			bound -= 2 * x
+		}
+		if bound > best+1e-9 {
+			best = bound
+			sinceImproved = 0
+		} else if sinceImproved++; sinceImproved >= 10 {
+			step /= 2
+			sinceImproved = 0
+		}
+
+		degree[0]++
+		degree[end]++
+		var norm float64
+		for _, d := range degree {
+			norm += float64((d - 2) * (d - 2))
+		}
+		if norm == 0 {
+			break // the 1-tree is a path, the bound is its cost
+		}
+		t := step * (float64(upper) - bound) / norm
+		if t <= 0 {
+			break // the bound reached the known path, it is optimal
+		}
+		for v, d := range degree {
+			pi[v] += t * float64(d-2)
+		}
+	}
+	// costs are whole numbers, so is the cost of the best path, rounding off float noise
+	return uint64(math.Ceil(best - 1e-6))
+}
@@ -0,0 +1,79 @@
+package route
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"eve-tour/distance"
+)
+
+// randomProblem is an asymmetric problem on n nodes with costs up to 20, and first hop costs from a start.
+func randomProblem(rng *rand.Rand, n int) (Problem[uint8], []uint8) {
+	p := Problem[uint8]{Systems: make([]uint32, n), Matrix: distance.New[uint8](uint(n))}
+	firstHopCosts := make([]uint8, n)
+	for i := range n {
+		p.Systems[i] = uint32(i)
+		firstHopCosts[i] = uint8(1 + rng.IntN(20))
+		for j := range n {
+			if i != j {
+				p.Matrix.Set(uint(i), uint(j), uint8(1+rng.IntN(20)))
+			}
+		}
+	}
+	return p, firstHopCosts
+}
+
+// optimalPath tries every order.
+func optimalPath(p Problem[uint8], firstHopCosts []uint8) uint64 {
+	order := make([]uint, len(p.Systems))
+	used := make([]bool, len(order))
+	best := uint64(distance.Max[uint8]())
+	var try func(k int)
+	try = func(k int) {
+		if k == len(order) {
+			best = min(best, p.PathCost(order, firstHopCosts))
+			return
+		}
+		for v := range order {
+			if !used[v] {
+				used[v] = true
+				order[k] = uint(v)
+				try(k + 1)
+				used[v] = false
+			}
+		}
+	}
+	try(0)
+	return best
+}
+
+func TestBoundsBelowOptimum(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for n := 1; n <= 6; n++ {
+		for range 50 {
+			p, firstHopCosts := randomProblem(rng, n)
+			for _, start := range [][]uint8{firstHopCosts, nil} {
+				opt := optimalPath(p, start)
+				c := pathCosts[uint8]{p: p, firstHopCosts: start}
+				if b := trivialBound(c); b > opt {
+					t.Fatalf("%d nodes, start %v: trivial bound %d above optimum %d\n%v", n, start != nil, b, opt, p.Matrix)
+				}
+				// aiming at the optimum makes the steps as big as they get
+				if b := heldKarp(c, 100, opt); b > opt {
+					t.Fatalf("%d nodes, start %v: Held-Karp bound %d above optimum %d\n%v", n, start != nil, b, opt, p.Matrix)
+				}
+			}
+		}
+	}
+}
+
+func TestGapWithoutBound(t *testing.T) {
+	b := Bounds{}
+	if g := b.Gap(0); g != 0 {
+		t.Errorf("gap of a free tour = %v, want 0", g)
+	}
+	if g := b.Gap(3); !math.IsInf(g, 1) {
+		t.Errorf("gap over a 0 bound = %v, want infinite", g)
+	}
+}
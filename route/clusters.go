@@ -0,0 +1,138 @@
+package route
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"eve-tour/distance"
+	"eve-tour/universe"
+)
+
+// Clustering is how GTSP sets are made, GLKH visits one node of each.
+type Clustering string
+
+const (
+	ByRegion        Clustering = "region"
+	ByConstellation Clustering = "constellation"
+	// ByStation makes every station a node and every system a set, to pick one station out of a system's many.
+	ByStation Clustering = "station"
+	// ByOwner makes every station a node and sets of the stations of each NPC corporation, like one agent per corporation.
+	ByOwner Clustering = "owner"
+	// ByGroups uses the groups of a file, each is visited at least once, see [ReadGroups].
+	ByGroups Clustering = "groups"
+)
+
+var Clusterings = []Clustering{ByRegion, ByConstellation, ByStation, ByOwner, ByGroups}
+
+// ParseClustering checks name is one of [Clusterings].
+func ParseClustering(name string) (Clustering, error) {
+	c := Clustering(strings.ToLower(name))
+	if !slices.Contains(Clusterings, c) {
+		return "", fmt.Errorf("unknown clustering %q", name)
+	}
+	return c, nil
+}
+
+// Group is a named set of systems.
+type Group struct {
+	Name    string
+	Systems []uint32
+}
+
+// ReadGroups reads one group per line, "name: system, system, ...", systems by name or ID.
+// The name is optional, # starts a comment.
+func ReadGroups(g universe.Graph, path string) ([]Group, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening groups: %w", err)
+	}
+	defer f.Close()
+
+	var groups []Group
+	var line int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		group := Group{Name: "line " + strconv.Itoa(line)}
+		if name, list, ok := strings.Cut(text, ":"); ok {
+			group.Name, text = strings.TrimSpace(name), list
+		}
+		for name := range strings.SplitSeq(text, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			id, ok := g.Lookup(name)
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: unknown system %q", path, line, name)
+			}
+			group.Systems = append(group.Systems, id)
+		}
+		groups = append(groups, group)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading groups: %w", err)
+	}
+	return groups, nil
+}
+
+// Clusters turns p into a GTSP instance, the returned problem is the one the sets index.
+// Station clusterings return a station level problem, see [Problem.Stations].
+// Groups may overlap: a system gets a node per group it is in, at no cost from each other, so one stop can satisfy several groups.
+// Groups without any system of p are dropped with a warning, GTSP sets can't be empty.
+func Clusters[T distance.Weight](g universe.Graph, p Problem[T], c Clustering, groups []Group, log *log.Logger) (Problem[T], [][]uint, error) {
+	switch c {
+	case ByRegion:
+		return p, p.RegionBuckets(g), nil
+	case ByConstellation:
+		return p, p.ConstellationBuckets(g), nil
+	case ByStation, ByOwner:
+		sp := NewStationProblem(g, p.Full, p.Systems)
+		if len(sp.Systems) == 0 {
+			return Problem[T]{}, nil, fmt.Errorf("none of the systems has stations")
+		}
+		if c == ByStation {
+			return sp, bucketsBy(len(sp.Systems), func(i int) uint32 { return sp.Systems[i] }), nil
+		}
+		return sp, bucketsBy(len(sp.Systems), func(i int) uint32 {
+			st, _ := g.Nodes[sp.Systems[i]].Station(sp.Stations[i])
+			return st.Owner
+		}), nil
+	case ByGroups:
+		in := make(map[uint32]struct{}, len(p.Systems))
+		for _, id := range p.Systems {
+			in[id] = struct{}{}
+		}
+		var systems []uint32
+		var buckets [][]uint
+		for _, group := range groups {
+			var bucket []uint
+			for _, id := range group.Systems {
+				if _, ok := in[id]; !ok {
+					continue
+				}
+				bucket = append(bucket, uint(len(systems)))
+				systems = append(systems, id)
+			}
+			if len(bucket) == 0 {
+				orDiscard(log).Println("warning: group", group.Name, "has no target system, skipping it")
+				continue
+			}
+			buckets = append(buckets, bucket)
+		}
+		if len(buckets) == 0 {
+			return Problem[T]{}, nil, fmt.Errorf("no group has a target system")
+		}
+		return NewWeightedProblem(g, p.Full, systems), buckets, nil
+	}
+	return Problem[T]{}, nil, fmt.Errorf("unknown clustering %q", c)
+}
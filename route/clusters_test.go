@@ -0,0 +1,91 @@
+package route
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"eve-tour/distance"
+	"eve-tour/universe"
+)
+
+// clusterGraph has Jita, Perimeter and Urlen in a row in The Forge, and Amarr far away.
+func clusterGraph() universe.Graph {
+	g := universe.Graph{
+		Nodes: map[uint32]universe.System{
+			1: {Name: "Jita", Region: "The Forge", Constellation: "Kimotoro", StationInfo: []universe.Station{{ID: 60003760, Owner: 1000035}, {ID: 60003466, Owner: 1000044}}},
+			2: {Name: "Perimeter", Region: "The Forge", Constellation: "Kimotoro", StationInfo: []universe.Station{{ID: 60000361, Owner: 1000035}}},
+			3: {Name: "Urlen", Region: "The Forge", Constellation: "Okkamon"},
+			4: {Name: "Amarr", Region: "Domain", Constellation: "Throne Worlds", StationInfo: []universe.Station{{ID: 60008494, Owner: 1000086}}},
+		},
+		IdsToMatrixIndexes: map[uint32]uint{1: 0, 2: 1, 3: 2, 4: 3},
+		MatrixIndexesToIds: []uint32{1, 2, 3, 4},
+		Matrix:             distance.NewD2(4),
+	}
+	for i := range uint(4) {
+		for j := range uint(4) {
+			g.Matrix.Set(i, j, uint8(max(i, j)-min(i, j))*3)
+		}
+	}
+	return g
+}
+
+func TestClusters(t *testing.T) {
+	g := clusterGraph()
+	p := NewProblem(g, []uint32{1, 2, 3, 4})
+	groups := []Group{
+		{Name: "hubs", Systems: []uint32{1, 4}},
+		{Name: "forge", Systems: []uint32{1, 2}},
+		{Name: "nowhere", Systems: []uint32{5}},
+	}
+	tests := []struct {
+		clustering Clustering
+		systems    []uint32
+		stations   []uint32
+		buckets    [][]uint
+	}{
+		{clustering: ByRegion, systems: []uint32{1, 2, 3, 4}, buckets: [][]uint{{0, 1, 2}, {3}}},
+		{clustering: ByConstellation, systems: []uint32{1, 2, 3, 4}, buckets: [][]uint{{0, 1}, {2}, {3}}},
+		{clustering: ByStation, systems: []uint32{1, 1, 2, 4}, stations: []uint32{60003760, 60003466, 60000361, 60008494}, buckets: [][]uint{{0, 1}, {2}, {3}}},
+		{clustering: ByOwner, systems: []uint32{1, 1, 2, 4}, stations: []uint32{60003760, 60003466, 60000361, 60008494}, buckets: [][]uint{{0, 2}, {1}, {3}}},
+		// Jita gets a node for each group it's in
+		{clustering: ByGroups, systems: []uint32{1, 4, 1, 2}, buckets: [][]uint{{0, 1}, {2, 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.clustering), func(t *testing.T) {
+			var warnings bytes.Buffer
+			cp, buckets, err := Clusters(g, p, tt.clustering, groups, log.New(&warnings, "", 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(cp.Systems, tt.systems) || !slices.Equal(cp.Stations, tt.stations) {
+				t.Errorf("nodes %v at stations %v, want %v at %v", cp.Systems, cp.Stations, tt.systems, tt.stations)
+			}
+			if !reflect.DeepEqual(buckets, tt.buckets) {
+				t.Errorf("sets %v, want %v", buckets, tt.buckets)
+			}
+			for i, a := range cp.Systems {
+				for j, b := range cp.Systems {
+					if want := g.Matrix.At(g.IdsToMatrixIndexes[a], g.IdsToMatrixIndexes[b]); cp.Matrix.At(uint(i), uint(j)) != want {
+						t.Fatalf("cost from node %d to %d is %d, want %d", i, j, cp.Matrix.At(uint(i), uint(j)), want)
+					}
+				}
+			}
+			if gotWarning := strings.Contains(warnings.String(), "group nowhere has no target"); gotWarning != (tt.clustering == ByGroups) {
+				t.Errorf("warnings: %q", warnings.String())
+			}
+		})
+	}
+
+	_, _, err := Clusters(g, p, ByGroups, groups[2:], nil)
+	if err == nil {
+		t.Error("no error without a group of targets")
+	}
+	_, _, err = Clusters(g, NewProblem(g, []uint32{3}), ByStation, nil, nil)
+	if err == nil {
+		t.Error("no error clustering stations of Urlen, which has none")
+	}
+}
@@ -0,0 +1,76 @@
+// Package route selects the systems to visit and orders them with LKH.
+package route
+
+import (
+	"strings"
+
+	"eve-tour/universe"
+)
+
+// Filter selects the target systems out of the graph.
+// Nil sets don't filter anything.
+type Filter struct {
+	// Regions, SkipRegions, Constellations and SkipConstellations hold lower-cased names, see [ParseNames].
+	Regions            map[string]struct{}
+	SkipRegions        map[string]struct{}
+	Constellations     map[string]struct{}
+	SkipConstellations map[string]struct{}
+	OnlyWithStations   bool
+	Visited            map[uint32]struct{}
+	// Predicate is an extra condition, usually a bound [query.Query], nil matches everything.
+	Predicate func(id uint32, system universe.System) bool
+}
+
+// ParseNames splits a comma separated list into a lower-cased set, an empty list returns nil.
+func ParseNames(list string) map[string]struct{} {
+	if list == "" {
+		return nil
+	}
+	names := make(map[string]struct{})
+	for name := range strings.SplitSeq(list, ",") {
+		names[strings.ToLower(strings.TrimSpace(name))] = struct{}{}
+	}
+	return names
+}
+
+func (f *Filter) Match(id uint32, system universe.System) bool {
+	if _, ok := f.Visited[id]; ok {
+		return false
+	}
+	if !included(f.Regions, f.SkipRegions, system.Region) {
+		return false
+	}
+	if !included(f.Constellations, f.SkipConstellations, system.Constellation) {
+		return false
+	}
+	if f.OnlyWithStations && len(system.Stations) == 0 {
+		return false
+	}
+	if f.Predicate != nil && !f.Predicate(id, system) {
+		return false
+	}
+	return true
+}
+
+// included applies an allow and a deny list of lower-cased names to name.
+func included(only, skip map[string]struct{}, name string) bool {
+	name = strings.ToLower(name)
+	if only != nil {
+		if _, ok := only[name]; !ok {
+			return false
+		}
+	}
+	_, skipped := skip[name]
+	return !skipped
+}
+
+// Targets returns the reachable systems matching f, in matrix order.
+func Targets(g universe.Graph, f Filter) []uint32 {
+	var targets []uint32
+	for _, v := range g.MatrixIndexesToIds {
+		if f.Match(v, g.Nodes[v]) {
+			targets = append(targets, v)
+		}
+	}
+	return targets
+}
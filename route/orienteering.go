@@ -0,0 +1,214 @@
+package route
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"eve-tour/distance"
+	"eve-tour/universe"
+)
+
+// ReadScores reads "system: score" lines, # starts a comment.
+func ReadScores(g universe.Graph, path string) (map[uint32]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening scores: %w", err)
+	}
+	defer f.Close()
+
+	scores := make(map[uint32]float64)
+	var line int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected \"system: score\"", path, line)
+		}
+		id, ok := g.Lookup(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unknown system %q", path, line, strings.TrimSpace(name))
+		}
+		score, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || score < 0 {
+			return nil, fmt.Errorf("%s:%d: invalid score %q", path, line, strings.TrimSpace(value))
+		}
+		scores[id] = score
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading scores: %w", err)
+	}
+	return scores, nil
+}
+
+// Scores returns the score of every node, systems missing from bySystem are worth 1.
+func (p Problem[T]) Scores(bySystem map[uint32]float64) []float64 {
+	scores := make([]float64, len(p.Systems))
+	for i, id := range p.Systems {
+		score, ok := bySystem[id]
+		if !ok {
+			score = 1
+		}
+		scores[i] = score
+	}
+	return scores
+}
+
+// PathCost is the cost of visiting order, from the start if firstHopCosts isn't nil.
+// It is [distance.Max] if a leg is unreachable.
+func (p Problem[T]) PathCost(order []uint, firstHopCosts []T) uint64 {
+	var cost uint64
+	for i, v := range order {
+		var leg T
+		if i > 0 {
+			leg = p.Matrix.At(order[i-1], v)
+		} else if firstHopCosts != nil {
+			leg = firstHopCosts[v]
+		}
+		if leg == distance.Max[T]() {
+			return uint64(distance.Max[T]())
+		}
+		cost += uint64(leg)
+	}
+	return cost
+}
+
+// orienteer holds a path being grown within the budget.
+type orienteer[T distance.Weight] struct {
+	p             Problem[T]
+	firstHopCosts []T
+	budget        uint64
+	path          []uint
+	cost          uint64
+}
+
+// leg is the cost from position i of the path to node n, -1 being the start.
+// ok is false for unreachable pairs.
+func (o *orienteer[T]) leg(i int, n uint) (uint64, bool) {
+	var c T
+	switch {
+	case i >= 0:
+		c = o.p.Matrix.At(o.path[i], n)
+	case o.firstHopCosts != nil:
+		c = o.firstHopCosts[n]
+	}
+	return uint64(c), c != distance.Max[T]()
+}
+
+// insertionCost is the cost added by visiting n before position i of the path.
+func (o *orienteer[T]) insertionCost(n uint, i int) (uint64, bool) {
+	in, ok := o.leg(i-1, n)
+	if !ok {
+		return 0, false
+	}
+	if i == len(o.path) {
+		return in, true
+	}
+	out := o.p.Matrix.At(n, o.path[i])
+	if out == distance.Max[T]() {
+		return 0, false
+	}
+	old, _ := o.leg(i-1, o.path[i])
+	if in+uint64(out) < old {
+		return 0, true // the matrix isn't a metric, a detour can't be cheaper than nothing
+	}
+	return in + uint64(out) - old, true
+}
+
+// twoOpt reverses segments of the path while that shortens it.
+// Costs may be asymmetric, a reversed segment is priced with the legs walked backwards.
+func (o *orienteer[T]) twoOpt() {
+	for improved := true; improved; {
+		improved = false
+		// forward[k] and backward[k] sum the legs between the first k+1 nodes, each way
+		forward := make([]uint64, len(o.path))
+		backward := make([]uint64, len(o.path))
+		for k := 1; k < len(o.path); k++ {
+			f, b := o.p.Matrix.At(o.path[k-1], o.path[k]), o.p.Matrix.At(o.path[k], o.path[k-1])
+			if f == distance.Max[T]() || b == distance.Max[T]() {
+				return // unreachable legs would overflow the sums, don't bother
+			}
+			forward[k] = forward[k-1] + uint64(f)
+			backward[k] = backward[k-1] + uint64(b)
+		}
+		for i := 0; i < len(o.path)-1 && !improved; i++ {
+			for j := i + 1; j < len(o.path); j++ {
+				oldIn, _ := o.leg(i-1, o.path[i])
+				newIn, ok := o.leg(i-1, o.path[j])
+				if !ok {
+					continue
+				}
+				oldCost := oldIn + forward[j] - forward[i]
+				newCost := newIn + backward[j] - backward[i]
+				if j+1 < len(o.path) {
+					oldOut := o.p.Matrix.At(o.path[j], o.path[j+1])
+					newOut := o.p.Matrix.At(o.path[i], o.path[j+1])
+					if newOut == distance.Max[T]() {
+						continue
+					}
+					oldCost += uint64(oldOut)
+					newCost += uint64(newOut)
+				}
+				if newCost < oldCost {
+					slices.Reverse(o.path[i : j+1])
+					o.cost = o.cost - oldCost + newCost
+					improved = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// Orienteer picks the nodes worth the most score within budget and returns them in visiting order.
+// The budget is in the unit of the matrix, jumps or seconds, and counts from the start if firstHopCosts isn't nil.
+//
+// Nodes are added where they cost the least, best score per cost first, and the path is shortened with 2-opt whenever nothing fits anymore.
+// The order is only a starting point, solving the picked nodes as a SOP usually shortens it further.
+func Orienteer[T distance.Weight](p Problem[T], firstHopCosts []T, scores []float64, budget uint64) []uint {
+	o := &orienteer[T]{p: p, firstHopCosts: firstHopCosts, budget: budget}
+	picked := make([]bool, len(p.Systems))
+	for {
+		var bestNode uint
+		var bestPos int
+		var bestCost uint64
+		var bestRatio float64
+		var found bool
+		for n := range p.Systems {
+			if picked[n] || scores[n] <= 0 {
+				continue
+			}
+			for i := 0; i <= len(o.path); i++ {
+				c, ok := o.insertionCost(uint(n), i)
+				if !ok || o.cost+c > budget {
+					continue
+				}
+				ratio := scores[n] / float64(max(c, 1))
+				if !found || ratio > bestRatio || ratio == bestRatio && c < bestCost {
+					bestNode, bestPos, bestCost, bestRatio, found = uint(n), i, c, ratio, true
+				}
+			}
+		}
+		if found {
+			o.path = append(o.path[:bestPos], append([]uint{bestNode}, o.path[bestPos:]...)...)
+			o.cost = p.PathCost(o.path, firstHopCosts)
+			picked[bestNode] = true
+			continue
+		}
+
+		// nothing fits, see if a shorter path makes room
+		before := o.cost
+		o.twoOpt()
+		if o.cost == before {
+			return o.path
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package route
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestOrienteerFitsBudget(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	for n := 1; n <= 8; n++ {
+		for range 50 {
+			p, firstHopCosts := randomProblem(rng, n)
+			scores := make([]float64, n)
+			for i := range scores {
+				scores[i] = float64(rng.IntN(5))
+			}
+			budget := uint64(rng.IntN(60))
+			for _, start := range [][]uint8{firstHopCosts, nil} {
+				path := Orienteer(p, start, scores, budget)
+				if cost := p.PathCost(path, start); cost > budget {
+					t.Fatalf("%d nodes, start %v: path %v costs %d, over the budget of %d", n, start != nil, path, cost, budget)
+				}
+				seen := make(map[uint]bool)
+				for _, v := range path {
+					if seen[v] || scores[v] <= 0 {
+						t.Fatalf("path %v visits %d twice or for nothing, scores %v", path, v, scores)
+					}
+					seen[v] = true
+				}
+			}
+		}
+	}
+}
+
+func TestTwoOptAsymmetric(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+	for n := 2; n <= 8; n++ {
+		for range 50 {
+			p, firstHopCosts := randomProblem(rng, n)
+			for _, start := range [][]uint8{firstHopCosts, nil} {
+				path := make([]uint, n)
+				for i, v := range rng.Perm(n) {
+					path[i] = uint(v)
+				}
+				before := p.PathCost(path, start)
+				o := &orienteer[uint8]{p: p, firstHopCosts: start, budget: before, path: slices.Clone(path), cost: before}
+				o.twoOpt()
+				// the tracked cost is what the budget is checked against, it must not drift from the real one
+				if cost := p.PathCost(o.path, start); cost != o.cost || cost > before {
+					t.Fatalf("%d nodes, start %v: %v (cost %d) became %v, costing %d but tracked as %d", n, start != nil, path, before, o.path, cost, o.cost)
+				}
+				sorted := slices.Clone(o.path)
+				slices.Sort(sorted)
+				for i, v := range sorted {
+					if v != uint(i) {
+						t.Fatalf("2-opt turned %v into %v", path, o.path)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"eve-tour/tsplib"
+)
+
+// instance is one of the parallel runs of a solver, in its own directory.
+type instance[R any] struct {
+	dir  string
+	seed int
+	tour R
+	cost uint64
+	err  error
+	// keep leaves dir behind to look at what went wrong.
+	keep bool
+}
+
+// solveParallel is [solve] starting s.Parallel instances with consecutive seeds, from the one in the parameter file.
+// They share the problem file written in s.Dir. If ctx is cancelled every instance is killed and the best tour already written is kept.
+// The directories of instances that failed are left behind with their lkh.log, interrupted ones aren't.
+func solveParallel[R any](ctx context.Context, s Solver, writeProblem func(path string) error, initialTour []int, read func(path string) (R, uint64, error)) (R, error) {
+	var zero R
+	params, err := tsplib.ReadParamsFile(s.ParFile)
+	if err != nil {
+		return zero, fmt.Errorf("reading graph.par: %w", err)
+	}
+	problemFile, err := filepath.Abs(filepath.Join(s.Dir, "graph.tsp"))
+	if err != nil {
+		return zero, fmt.Errorf("locating problem: %w", err)
+	}
+	err = writeProblem(problemFile)
+	if err != nil {
+		return zero, fmt.Errorf("writing problem: %w", err)
+	}
+	params.Set("PROBLEM_FILE", problemFile)
+	params.Set("TOUR_FILE", "output.tour")
+	if initialTour != nil {
+		initialFile, err := filepath.Abs(filepath.Join(s.Dir, "initial.tour"))
+		if err != nil {
+			return zero, fmt.Errorf("locating initial tour: %w", err)
+		}
+		t := &tsplib.Tour{Name: "initial", Nodes: initialTour}
+		err = t.WriteFile(initialFile)
+		if err != nil {
+			return zero, fmt.Errorf("writing initial tour: %w", err)
+		}
+		params.Set("INITIAL_TOUR_FILE", initialFile)
+	}
+	seed := 1 // LKH's default
+	if v, ok := params.Get("SEED"); ok {
+		seed, err = strconv.Atoi(v)
+		if err != nil {
+			return zero, fmt.Errorf("invalid SEED %q in %s", v, s.ParFile)
+		}
+	}
+	executable, err := filepath.Abs(filepath.Join(s.Dir, s.Executable))
+	if err != nil {
+		return zero, fmt.Errorf("locating %s: %w", s.Executable, err)
+	}
+
+	instances := make([]*instance[R], s.Parallel)
+	for i := range instances {
+		dir, err := os.MkdirTemp(s.Dir, "run-")
+		if err != nil {
+			return zero, fmt.Errorf("creating run directory: %w", err)
+		}
+		in := &instance[R]{dir: dir, seed: seed + i}
+		instances[i] = in
+		defer func() {
+			if !in.keep {
+				os.RemoveAll(in.dir)
+			}
+		}()
+		params.Set("SEED", strconv.Itoa(in.seed))
+		err = params.WriteFile(filepath.Join(dir, "graph.par"))
+		if err != nil {
+			return zero, fmt.Errorf("writing graph.par: %w", err)
+		}
+	}
+
+	l := orDiscard(s.Log)
+	l.Println("running", s.Parallel, s.Executable, "at once, seeds", seed, "to", seed+s.Parallel-1)
+	var wg sync.WaitGroup
+	for _, in := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			in.run(ctx, s, executable, read)
+		}()
+	}
+	wg.Wait()
+
+	var best *instance[R]
+	var errs []error
+	for _, in := range instances {
+		if in.err != nil {
+			l.Printf("seed %d: %v", in.seed, in.err)
+			errs = append(errs, fmt.Errorf("seed %d: %w", in.seed, in.err))
+			continue
+		}
+		l.Printf("seed %d: cost %d", in.seed, in.cost)
+		if best == nil || in.cost < best.cost {
+			best = in
+		}
+	}
+	if best == nil {
+		return zero, errors.Join(errs...)
+	}
+	if ctx.Err() != nil {
+		l.Println("interrupted, keeping the best tour found, seed", best.seed)
+	}
+	return best.tour, nil
+}
+
+// run solves in the instance's directory and reads the tour back, even when interrupted since it may have been written already.
+func (in *instance[R]) run(ctx context.Context, s Solver, executable string, read func(path string) (R, uint64, error)) {
+	log, err := os.Create(filepath.Join(in.dir, "lkh.log"))
+	if err != nil {
+		in.err = fmt.Errorf("creating log: %w", err)
+		return
+	}
+	defer log.Close()
+
+	cmd := exec.CommandContext(ctx, executable, "graph.par")
+	cmd.Dir = in.dir
+	cmd.Stdout = log
+	cmd.Stderr = log
+	runErr := cmd.Run()
+	if runErr != nil && ctx.Err() == nil {
+		in.err = fmt.Errorf("running %s, see %s: %w", s.Executable, log.Name(), runErr)
+		in.keep = true
+		return
+	}
+
+	in.tour, in.cost, in.err = read(filepath.Join(in.dir, "output.tour"))
+	if in.err != nil && ctx.Err() != nil {
+		in.err = fmt.Errorf("interrupted: %w", in.err)
+		return
+	}
+	in.keep = in.err != nil
+}
@@ -0,0 +1,94 @@
+package route
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubSolver writes a tour costing 5, 2 and 4 for seeds 1 to 3, later seeds hang until killed.
+func stubSolver(t *testing.T, parallel int) Solver {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub solver is a shell script")
+	}
+	dir := t.TempDir()
+	script := `#!/bin/sh
+seed=$(sed -n 's/^SEED = //p' graph.par)
+case $seed in
+1) echo 5 > output.tour ;;
+2) echo 2 > output.tour ;;
+3) echo 4 > output.tour ;;
+*) exec sleep 60 ;;
+esac
+`
+	err := os.WriteFile(filepath.Join(dir, "stub"), []byte(script), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "graph.par"), []byte("RUNS = 1\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Solver{Dir: dir, Executable: "stub", ParFile: filepath.Join(dir, "graph.par"), Parallel: parallel}
+}
+
+func readCost(path string) (uint64, uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	cost, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	return cost, cost, err
+}
+
+func writeNothing(string) error { return nil }
+
+// runsLeft lists the instance directories left behind.
+func runsLeft(t *testing.T, s Solver) []string {
+	t.Helper()
+	runs, err := filepath.Glob(filepath.Join(s.Dir, "run-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runs
+}
+
+func TestSolveParallelKeepsBest(t *testing.T) {
+	s := stubSolver(t, 3)
+	cost, err := solveParallel(context.Background(), s, writeNothing, nil, readCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost != 2 {
+		t.Errorf("kept the tour costing %d, want seed 2's costing 2", cost)
+	}
+	if runs := runsLeft(t, s); len(runs) != 0 {
+		t.Errorf("left %v behind", runs)
+	}
+}
+
+func TestSolveParallelInterrupted(t *testing.T) {
+	s := stubSolver(t, 5)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	cost, err := solveParallel(ctx, s, writeNothing, nil, readCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Errorf("took %v, the hanging instances weren't killed", elapsed)
+	}
+	if cost != 2 {
+		t.Errorf("kept the tour costing %d, want seed 2's costing 2", cost)
+	}
+	if runs := runsLeft(t, s); len(runs) != 0 {
+		t.Errorf("left %v behind", runs)
+	}
+}
@@ -0,0 +1,187 @@
+package route
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"eve-tour/query"
+	"eve-tour/tsplib"
+	"eve-tour/universe"
+)
+
+// Constraint orders the targets: every target matching Before is visited ahead of every target matching After.
+type Constraint struct {
+	// Text is the constraint as written, for errors.
+	Text          string
+	Before, After func(id uint32, s universe.System) bool
+}
+
+// ParseConstraint parses "A before B", A and B are system names or queries, like "region = 'The Forge' before region = Lonetrek".
+func ParseConstraint(g universe.Graph, text string) (Constraint, error) {
+	a, b, ok := cutKeyword(text, "before")
+	if !ok {
+		return Constraint{}, fmt.Errorf("constraint %q: expected \"A before B\"", text)
+	}
+	c := Constraint{Text: text}
+	var err error
+	c.Before, err = side(g, a)
+	if err != nil {
+		return Constraint{}, fmt.Errorf("constraint %q: %w", text, err)
+	}
+	c.After, err = side(g, b)
+	if err != nil {
+		return Constraint{}, fmt.Errorf("constraint %q: %w", text, err)
+	}
+	return c, nil
+}
+
+// cutKeyword splits around the first word outside of quotes, case-insensitively.
+func cutKeyword(text, word string) (before, after string, ok bool) {
+	var quote rune
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case len(text)-i >= len(word) && strings.EqualFold(text[i:i+len(word)], word):
+			atStart := i == 0 || text[i-1] == ' ' || text[i-1] == ')'
+			end := i + len(word)
+			atEnd := end == len(text) || text[end] == ' ' || text[end] == '('
+			if atStart && atEnd {
+				return strings.TrimSpace(text[:i]), strings.TrimSpace(text[end:]), true
+			}
+		}
+	}
+	return "", "", false
+}
+
+func side(g universe.Graph, text string) (func(id uint32, s universe.System) bool, error) {
+	if id, ok := g.Lookup(strings.Trim(text, `"'`)); ok {
+		return func(other uint32, _ universe.System) bool { return other == id }, nil
+	}
+	q, err := query.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return q.Bind(g)
+}
+
+// ReadConstraints reads one constraint per line, # starts a comment.
+func ReadConstraints(g universe.Graph, path string) ([]Constraint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening constraints: %w", err)
+	}
+	defer f.Close()
+
+	var constraints []Constraint
+	var line int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		c, err := ParseConstraint(g, text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		constraints = append(constraints, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading constraints: %w", err)
+	}
+	return constraints, nil
+}
+
+// Precedences resolves constraints to the nodes of p.
+// Constraints with a side matching none of p's nodes are dropped with a warning to log, nil keeps quiet.
+// Cycles are an error since LKH can't satisfy them.
+func (p Problem[T]) Precedences(g universe.Graph, constraints []Constraint, log *log.Logger) ([]tsplib.Precedence, error) {
+	var precedences []tsplib.Precedence
+	for _, c := range constraints {
+		var before, after []uint
+		for i, id := range p.Systems {
+			if c.Before(id, g.Nodes[id]) {
+				before = append(before, uint(i))
+			}
+			if c.After(id, g.Nodes[id]) {
+				after = append(after, uint(i))
+			}
+		}
+		if len(before) == 0 || len(after) == 0 {
+			orDiscard(log).Println("warning: constraint", c.Text, "matches no target on one side, ignoring it")
+			continue
+		}
+		for _, b := range before {
+			for _, a := range after {
+				if a != b {
+					precedences = append(precedences, tsplib.Precedence{Before: b, After: a})
+				}
+			}
+		}
+	}
+
+	if cycle := findCycle(uint(len(p.Systems)), precedences); cycle != nil {
+		names := make([]string, len(cycle))
+		for i, v := range cycle {
+			names[i] = g.Nodes[p.Systems[v]].Name
+		}
+		return nil, fmt.Errorf("precedence constraints form a cycle: %s", strings.Join(names, " before "))
+	}
+	return precedences, nil
+}
+
+// findCycle returns the nodes of a cycle of the precedence graph, nil if it is acyclic.
+func findCycle(n uint, precedences []tsplib.Precedence) []uint {
+	next := make([][]uint, n)
+	for _, p := range precedences {
+		next[p.Before] = append(next[p.Before], p.After)
+	}
+
+	const (
+		unseen = iota
+		onStack
+		done
+	)
+	state := make([]uint8, n)
+	var stack []uint
+	var visit func(v uint) []uint
+	visit = func(v uint) []uint {
+		state[v] = onStack
+		stack = append(stack, v)
+		for _, w := range next[v] {
+			switch state[w] {
+			case onStack:
+				i := len(stack) - 1
+				for stack[i] != w {
+					i--
+				}
+				return append(stack[i:len(stack):len(stack)], w)
+			case unseen:
+				if cycle := visit(w); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[v] = done
+		return nil
+	}
+	for v := range n {
+		if state[v] == unseen {
+			if cycle := visit(v); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
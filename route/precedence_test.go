@@ -0,0 +1,68 @@
+package route
+
+import (
+	"bytes"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+
+	"eve-tour/tsplib"
+	"eve-tour/universe"
+)
+
+func TestPrecedencesCycle(t *testing.T) {
+	g := universe.Graph{Nodes: map[uint32]universe.System{
+		1: {Name: "Jita"},
+		2: {Name: "Perimeter"},
+		3: {Name: "Urlen"},
+		4: {Name: "Amarr"},
+	}}
+	p := Problem[uint8]{Systems: []uint32{1, 2, 3}}
+	parse := func(texts ...string) []Constraint {
+		var constraints []Constraint
+		for _, text := range texts {
+			c, err := ParseConstraint(g, text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			constraints = append(constraints, c)
+		}
+		return constraints
+	}
+
+	var warnings bytes.Buffer
+	precedences, err := p.Precedences(g, parse("Jita before Perimeter", "Perimeter before Urlen", "Amarr before Jita"), log.New(&warnings, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tsplib.Precedence{{Before: 0, After: 1}, {Before: 1, After: 2}}
+	if !slices.Equal(precedences, want) {
+		t.Errorf("precedences = %v, want %v", precedences, want)
+	}
+	if !strings.Contains(warnings.String(), "Amarr before Jita matches no target") {
+		t.Errorf("warnings %q don't mention the constraint on Amarr, which isn't a target", warnings.String())
+	}
+
+	_, err = p.Precedences(g, parse("Jita before Perimeter", "Perimeter before Urlen", "Urlen before Jita"), nil)
+	if err == nil || !strings.Contains(err.Error(), "cycle: Jita before Perimeter before Urlen before Jita") {
+		t.Errorf("got %v, want the cycle named", err)
+	}
+}
+
+func TestFindCycle(t *testing.T) {
+	tests := []struct {
+		precedences []tsplib.Precedence
+		want        []uint
+	}{
+		{precedences: []tsplib.Precedence{{Before: 0, After: 1}, {Before: 0, After: 2}, {Before: 1, After: 2}}},
+		{precedences: []tsplib.Precedence{{Before: 0, After: 1}, {Before: 1, After: 0}}, want: []uint{0, 1, 0}},
+		// the cycle doesn't include the node it was reached from
+		{precedences: []tsplib.Precedence{{Before: 0, After: 1}, {Before: 1, After: 2}, {Before: 2, After: 3}, {Before: 3, After: 1}}, want: []uint{1, 2, 3, 1}},
+	}
+	for _, tt := range tests {
+		if got := findCycle(4, tt.precedences); !slices.Equal(got, tt.want) {
+			t.Errorf("findCycle(%v) = %v, want %v", tt.precedences, got, tt.want)
+		}
+	}
+}
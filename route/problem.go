@@ -0,0 +1,153 @@
+package route
+
+import (
+	"fmt"
+
+	"eve-tour/distance"
+	"eve-tour/universe"
+)
+
+// Problem is the distance matrix restricted to the systems we want to visit.
+type Problem[T distance.Weight] struct {
+	// Systems maps problem indexes to system IDs.
+	Systems []uint32
+	// MatrixIndexes maps problem indexes to indexes in Full.
+	MatrixIndexes []uint
+	Matrix        distance.Matrix[T]
+	// Stations is nil unless nodes are stations, then Stations[i] is the station of node i, in system Systems[i].
+	Stations []uint32
+	// Full is the matrix of every reachable system the problem was cut from, laid out like the graph's matrix.
+	Full distance.Matrix[T]
+}
+
+// NewProblem restricts the graph's jump count matrix to systems.
+func NewProblem(g universe.Graph, systems []uint32) Problem[uint8] {
+	return NewWeightedProblem(g, g.Matrix, systems)
+}
+
+// NewWeightedProblem restricts full to systems, full must be laid out like the graph's matrix.
+func NewWeightedProblem[T distance.Weight](g universe.Graph, full distance.Matrix[T], systems []uint32) Problem[T] {
+	matrixIndexes := make([]uint, len(systems))
+	for i, v := range systems {
+		matrixIndexes[i] = g.IdsToMatrixIndexes[v]
+	}
+
+	return Problem[T]{
+		Systems:       systems,
+		MatrixIndexes: matrixIndexes,
+		Matrix:        full.Sub(matrixIndexes),
+		Full:          full,
+	}
+}
+
+// NewStationProblem makes a node of every station of systems, nodes of the same system are at no cost from each other.
+// Systems without stations are left out.
+func NewStationProblem[T distance.Weight](g universe.Graph, full distance.Matrix[T], systems []uint32) Problem[T] {
+	var stationSystems, stations []uint32
+	for _, id := range systems {
+		for _, st := range g.Nodes[id].StationInfo {
+			stationSystems = append(stationSystems, id)
+			stations = append(stations, st.ID)
+		}
+	}
+	p := NewWeightedProblem(g, full, stationSystems)
+	p.Stations = stations
+	return p
+}
+
+// Narrow returns the problem restricted to order, in that order.
+func (p Problem[T]) Narrow(order []uint) Problem[T] {
+	systems := make([]uint32, len(order))
+	matrixIndexes := make([]uint, len(order))
+	var stations []uint32
+	if p.Stations != nil {
+		stations = make([]uint32, len(order))
+	}
+	for i, v := range order {
+		systems[i] = p.Systems[v]
+		matrixIndexes[i] = p.MatrixIndexes[v]
+		if stations != nil {
+			stations[i] = p.Stations[v]
+		}
+	}
+
+	return Problem[T]{
+		Systems:       systems,
+		MatrixIndexes: matrixIndexes,
+		Matrix:        p.Matrix.Sub(order),
+		Stations:      stations,
+		Full:          p.Full,
+	}
+}
+
+// Dedup drops the nodes standing for a system, or station, already in the problem.
+// Overlapping GTSP groups may pick the same system for several groups.
+func (p Problem[T]) Dedup() Problem[T] {
+	type node struct{ system, station uint32 }
+	seen := make(map[node]struct{}, len(p.Systems))
+	var keep []uint
+	for i, id := range p.Systems {
+		n := node{system: id}
+		if p.Stations != nil {
+			n.station = p.Stations[i]
+		}
+		if _, ok := seen[n]; ok {
+			continue
+		}
+		seen[n] = struct{}{}
+		keep = append(keep, uint(i))
+	}
+	if len(keep) == len(p.Systems) {
+		return p
+	}
+	return p.Narrow(keep)
+}
+
+// Waypoints returns the autopilot destinations of the nodes: stations for station problems, systems otherwise.
+func (p Problem[T]) Waypoints() []uint32 {
+	if p.Stations != nil {
+		return p.Stations
+	}
+	return p.Systems
+}
+
+// FirstHopCosts returns the costs from start to every system of the problem.
+func (p Problem[T]) FirstHopCosts(g universe.Graph, start uint32) ([]T, error) {
+	startMatrixIndex, ok := g.IdsToMatrixIndexes[start]
+	if !ok {
+		return nil, fmt.Errorf("start system %d not in matrix", start)
+	}
+
+	firstHopCosts := make([]T, len(p.MatrixIndexes))
+	for i, v := range p.MatrixIndexes {
+		firstHopCosts[i] = p.Full.At(startMatrixIndex, v)
+	}
+	return firstHopCosts, nil
+}
+
+// RegionBuckets groups the problem indexes by region, for GTSP.
+func (p Problem[T]) RegionBuckets(g universe.Graph) [][]uint {
+	return bucketsBy(len(p.Systems), func(i int) string { return g.Nodes[p.Systems[i]].Region })
+}
+
+// ConstellationBuckets groups the problem indexes by constellation, for GTSP.
+func (p Problem[T]) ConstellationBuckets(g universe.Graph) [][]uint {
+	return bucketsBy(len(p.Systems), func(i int) string { return g.Nodes[p.Systems[i]].Constellation })
+}
+
+// bucketsBy groups the problem indexes by key, buckets are in order of first appearance.
+func bucketsBy[K comparable](n int, key func(i int) K) [][]uint {
+	var buckets [][]uint
+	keyToBucket := make(map[K]uint)
+	for i := range n {
+		k := key(i)
+		bucket, ok := keyToBucket[k]
+		if !ok {
+			bucket = uint(len(buckets))
+			keyToBucket[k] = bucket
+			buckets = append(buckets, nil)
+		}
+		buckets[bucket] = append(buckets[bucket], uint(i))
+	}
+	return buckets
+}
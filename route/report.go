@@ -0,0 +1,123 @@
+package route
+
+import (
+	"fmt"
+
+	"eve-tour/distance"
+	"eve-tour/universe"
+)
+
+// Validate checks that order visits every node of p exactly once, as a solution of p must.
+func (p Problem[T]) Validate(order []uint) error {
+	if len(order) != len(p.Systems) {
+		return fmt.Errorf("tour visits %d nodes of %d", len(order), len(p.Systems))
+	}
+	seen := make([]bool, len(p.Systems))
+	for _, n := range order {
+		if n >= uint(len(seen)) {
+			return fmt.Errorf("tour visits node %d, there are %d", n, len(seen))
+		}
+		if seen[n] {
+			return fmt.Errorf("tour visits node %d twice", n)
+		}
+		seen[n] = true
+	}
+	return nil
+}
+
+// validateGTSP checks that order visits one node of each bucket.
+func validateGTSP(order []uint, nodes int, buckets [][]uint) error {
+	if len(order) != len(buckets) {
+		return fmt.Errorf("tour visits %d nodes for %d sets", len(order), len(buckets))
+	}
+	bucketOf := make([]int, nodes)
+	for i := range bucketOf {
+		bucketOf[i] = -1
+	}
+	for b, bucket := range buckets {
+		for _, n := range bucket {
+			bucketOf[n] = b
+		}
+	}
+	visited := make([]bool, len(buckets))
+	for _, n := range order {
+		if n >= uint(nodes) || bucketOf[n] < 0 {
+			return fmt.Errorf("tour visits node %d, in no set", n)
+		}
+		if visited[bucketOf[n]] {
+			return fmt.Errorf("tour visits set %d twice", bucketOf[n])
+		}
+		visited[bucketOf[n]] = true
+	}
+	return nil
+}
+
+// Leg goes from one node of a tour to the next, From is 0 for the leg from the start.
+type Leg struct {
+	From, To uint32
+	Jumps    int
+	// Cost is in the unit of the problem's matrix, jumps or seconds.
+	Cost uint64
+}
+
+// RegionCost is the part of a tour spent reaching the targets of one region.
+type RegionCost struct {
+	Region  string
+	Targets int
+	Jumps   int
+	Cost    uint64
+}
+
+// Report is what a tour costs, legs reaching a region count for it.
+type Report struct {
+	Targets int
+	Jumps   int
+	Cost    uint64
+	Longest Leg
+	// Regions are in the order the tour first enters them, a region entered twice is only listed once.
+	Regions []RegionCost
+}
+
+// Report costs order, a validated solution of p, from start if firstHopCosts isn't nil.
+// Jumps are counted on the graph whatever the problem's matrix is. An unreachable leg is an error, LKH only takes one if it has no choice.
+func (p Problem[T]) Report(g universe.Graph, order []uint, firstHopCosts []T, start uint32) (Report, error) {
+	r := Report{Targets: len(order)}
+	byRegion := make(map[string]int)
+	for i, n := range order {
+		leg := Leg{To: p.Systems[n]}
+		var cost T
+		switch {
+		case i > 0:
+			leg.From = p.Systems[order[i-1]]
+			cost = p.Matrix.At(order[i-1], n)
+		case firstHopCosts != nil:
+			leg.From = start
+			cost = firstHopCosts[n]
+		}
+		if leg.From != 0 {
+			jumps := g.Matrix.At(g.IdsToMatrixIndexes[leg.From], g.IdsToMatrixIndexes[leg.To])
+			if cost == distance.Max[T]() || jumps == distance.Infinity {
+				return Report{}, fmt.Errorf("tour goes from %s to %s, which can't reach each other", g.Nodes[leg.From].Name, g.Nodes[leg.To].Name)
+			}
+			leg.Jumps = int(jumps)
+			leg.Cost = uint64(cost)
+		}
+		r.Jumps += leg.Jumps
+		r.Cost += leg.Cost
+		if leg.From != 0 && (r.Longest.From == 0 || leg.Cost > r.Longest.Cost) {
+			r.Longest = leg
+		}
+
+		region := g.Nodes[leg.To].Region
+		j, ok := byRegion[region]
+		if !ok {
+			j = len(r.Regions)
+			byRegion[region] = j
+			r.Regions = append(r.Regions, RegionCost{Region: region})
+		}
+		r.Regions[j].Targets++
+		r.Regions[j].Jumps += leg.Jumps
+		r.Regions[j].Cost += leg.Cost
+	}
+	return r, nil
+}
@@ -0,0 +1,86 @@
+package route
+
+import (
+	"strings"
+	"testing"
+
+	"eve-tour/distance"
+	"eve-tour/universe"
+)
+
+func TestValidate(t *testing.T) {
+	p := Problem[uint8]{Systems: []uint32{1, 2, 3}}
+	tests := []struct {
+		order []uint
+		err   string
+	}{
+		{order: []uint{2, 0, 1}},
+		{order: []uint{2, 0}, err: "visits 2 nodes of 3"},
+		{order: []uint{2, 0, 2}, err: "node 2 twice"},
+		{order: []uint{2, 0, 3}, err: "node 3, there are 3"},
+	}
+	for _, tt := range tests {
+		err := p.Validate(tt.order)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("Validate(%v) = %v, want %q", tt.order, err, tt.err)
+		}
+	}
+}
+
+func TestValidateGTSP(t *testing.T) {
+	// node 4 is in no set
+	buckets := [][]uint{{0, 1}, {2}, {3}}
+	tests := []struct {
+		order []uint
+		err   string
+	}{
+		{order: []uint{2, 1, 3}},
+		{order: []uint{2, 1}, err: "visits 2 nodes for 3 sets"},
+		{order: []uint{0, 2, 1}, err: "set 0 twice"},
+		{order: []uint{0, 2, 4}, err: "node 4, in no set"},
+		{order: []uint{0, 2, 5}, err: "node 5, in no set"},
+	}
+	for _, tt := range tests {
+		err := validateGTSP(tt.order, 5, buckets)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("validateGTSP(%v) = %v, want %q", tt.order, err, tt.err)
+		}
+	}
+}
+
+func TestReportUnreachable(t *testing.T) {
+	// Jita and Perimeter are next to each other, Thera can't be reached from either
+	g := universe.Graph{
+		Nodes: map[uint32]universe.System{
+			1: {Name: "Jita", Region: "The Forge"},
+			2: {Name: "Perimeter", Region: "The Forge"},
+			3: {Name: "Thera", Region: "G-R00031"},
+		},
+		IdsToMatrixIndexes: map[uint32]uint{1: 0, 2: 1, 3: 2},
+		Matrix:             distance.NewD2(3),
+	}
+	for i := range uint(3) {
+		for j := range uint(3) {
+			if i != j {
+				g.Matrix.Set(i, j, distance.Infinity)
+			}
+		}
+	}
+	g.Matrix.Set(0, 1, 1)
+	g.Matrix.Set(1, 0, 1)
+	p := NewWeightedProblem(g, g.Matrix, []uint32{1, 2, 3})
+
+	r, err := p.Report(g, []uint{0, 1}, nil, 0)
+	if err != nil || r.Jumps != 1 || r.Longest.From != 1 || len(r.Regions) != 1 || r.Regions[0].Targets != 2 {
+		t.Errorf("Report of Jita to Perimeter = %+v, %v", r, err)
+	}
+	_, err = p.Report(g, []uint{0, 1, 2}, nil, 0)
+	if err == nil || !strings.Contains(err.Error(), "from Perimeter to Thera") {
+		t.Errorf("leg to Thera: got %v, want it named", err)
+	}
+	firstHopCosts := []uint8{0, 1, distance.Infinity}
+	_, err = p.Report(g, []uint{2}, firstHopCosts, 1)
+	if err == nil || !strings.Contains(err.Error(), "from Jita to Thera") {
+		t.Errorf("first leg to Thera: got %v, want it named", err)
+	}
+}
@@ -0,0 +1,110 @@
+package route
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+
+	"eve-tour/universe"
+)
+
+// LastTourFile keeps the last solution, the next run starts from it instead of from scratch.
+const LastTourFile = "last-tour.json"
+
+// LastTour is a solution as saved between runs, MatrixIndexes tell whether the graph changed since.
+type LastTour struct {
+	Systems       []uint32 `json:"systems"`
+	MatrixIndexes []uint   `json:"matrix_indexes"`
+	// Stations is nil unless the tour went to stations.
+	Stations []uint32 `json:"stations,omitempty"`
+}
+
+// LastTour returns the problem's nodes as a tour to save, p should be narrowed to the solution.
+func (p Problem[T]) LastTour() LastTour {
+	return LastTour{Systems: p.Systems, MatrixIndexes: p.MatrixIndexes, Stations: p.Stations}
+}
+
+// LoadLastTour reads the last solution, ok is false if there is none.
+func LoadLastTour(fileName string) (t LastTour, ok bool, err error) {
+	b, err := os.ReadFile(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return LastTour{}, false, nil
+	}
+	if err != nil {
+		return LastTour{}, false, fmt.Errorf("reading last tour: %w", err)
+	}
+	err = json.Unmarshal(b, &t)
+	if err != nil {
+		return LastTour{}, false, fmt.Errorf("decoding last tour: %w", err)
+	}
+	if len(t.MatrixIndexes) != len(t.Systems) || t.Stations != nil && len(t.Stations) != len(t.Systems) {
+		return LastTour{}, false, fmt.Errorf("decoding last tour: %d systems but %d matrix indexes and %d stations", len(t.Systems), len(t.MatrixIndexes), len(t.Stations))
+	}
+	return t, true, nil
+}
+
+// SaveLastTour writes t for [LoadLastTour] to find on the next run.
+func SaveLastTour(fileName string, t LastTour) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("encoding last tour: %w", err)
+	}
+	err = os.WriteFile(fileName, b, 0o644)
+	if err != nil {
+		return fmt.Errorf("writing last tour: %w", err)
+	}
+	return nil
+}
+
+// Seed orders every node of p after t, to start the solver from: nodes of t still in p keep their order, visited ones are gone
+// and new ones are inserted where they cost the least. kept counts the nodes taken from t.
+// It is nil if t has nothing in common with p or was saved with another graph, its matrix indexes would mean other systems.
+func (p Problem[T]) Seed(g universe.Graph, t LastTour, firstHopCosts []T) (order []uint, kept int) {
+	// nodes are stations in station problems, a system can be several of them
+	key := func(systems, stations []uint32, i int) uint32 {
+		if stations != nil {
+			return stations[i]
+		}
+		return systems[i]
+	}
+	nodes := make(map[uint32]uint, len(p.Systems))
+	for i := range p.Systems {
+		nodes[key(p.Systems, p.Stations, i)] = uint(i)
+	}
+
+	o := &orienteer[T]{p: p, firstHopCosts: firstHopCosts}
+	seen := make([]bool, len(p.Systems))
+	for i, id := range t.Systems {
+		if m, ok := g.IdsToMatrixIndexes[id]; !ok || m != t.MatrixIndexes[i] {
+			return nil, 0
+		}
+		n, ok := nodes[key(t.Systems, t.Stations, i)]
+		if !ok || seen[n] {
+			continue
+		}
+		o.path = append(o.path, n)
+		seen[n] = true
+	}
+	kept = len(o.path)
+	if kept == 0 {
+		return nil, 0
+	}
+
+	for n := range p.Systems {
+		if seen[n] {
+			continue
+		}
+		best, bestCost := len(o.path), uint64(math.MaxUint64) // appended if unreachable from everywhere
+		for i := 0; i <= len(o.path); i++ {
+			c, ok := o.insertionCost(uint(n), i)
+			if ok && c < bestCost {
+				best, bestCost = i, c
+			}
+		}
+		o.path = append(o.path[:best], append([]uint{uint(n)}, o.path[best:]...)...)
+	}
+	return o.path, kept
+}
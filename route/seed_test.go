@@ -0,0 +1,47 @@
+package route
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSeed(t *testing.T) {
+	g := clusterGraph()
+	tour := func(systems ...uint32) LastTour {
+		last := LastTour{Systems: systems}
+		for _, id := range systems {
+			last.MatrixIndexes = append(last.MatrixIndexes, g.IdsToMatrixIndexes[id])
+		}
+		return last
+	}
+	moved := tour(1, 4)
+	moved.MatrixIndexes[1] = 2
+	stations := tour(1, 1)
+	stations.Stations = []uint32{60003466, 60003760}
+
+	tests := []struct {
+		name          string
+		p             Problem[uint8]
+		last          LastTour
+		firstHopCosts []uint8
+		want          []uint
+		kept          int
+	}{
+		// Perimeter lies between Amarr and Jita
+		{name: "new node inserted", p: NewProblem(g, []uint32{1, 2, 4}), last: tour(4, 1), want: []uint{2, 1, 0}, kept: 2},
+		{name: "visited nodes gone", p: NewProblem(g, []uint32{1, 4}), last: tour(1, 3, 4), want: []uint{0, 1}, kept: 2},
+		{name: "from the start", p: NewProblem(g, []uint32{1, 2}), last: tour(1), firstHopCosts: []uint8{0, 3}, want: []uint{0, 1}, kept: 1},
+		{name: "without a start", p: NewProblem(g, []uint32{1, 2}), last: tour(1), want: []uint{1, 0}, kept: 1},
+		{name: "nothing in common", p: NewProblem(g, []uint32{1, 2}), last: tour(3)},
+		{name: "other graph", p: NewProblem(g, []uint32{1, 4}), last: moved},
+		{name: "stations", p: NewStationProblem(g, g.Matrix, []uint32{1}), last: stations, want: []uint{1, 0}, kept: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, kept := tt.p.Seed(g, tt.last, tt.firstHopCosts)
+			if !slices.Equal(order, tt.want) || kept != tt.kept {
+				t.Errorf("Seed = %v keeping %d, want %v keeping %d", order, kept, tt.want, tt.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package route
+
+import (
+	"eve-tour/distance"
+	"eve-tour/universe"
+)
+
+// SessionLimits bound one play session, zero means no limit.
+type SessionLimits struct {
+	Jumps int
+	// Seconds needs a travel time matrix.
+	Seconds uint64
+}
+
+// Session is a stretch of the route played in one go, ending docked.
+type Session struct {
+	// Targets are the route's nodes visited in the session, as indexes of the route.
+	Targets []int
+	// End is the safe system the session stops in, the last target itself or a detour away from it.
+	End uint32
+	// Detour is the jumps from the last target to End.
+	Detour int
+	// Jumps and Seconds count from where the previous session ended, Seconds is 0 without a travel time matrix.
+	Jumps   int
+	Seconds uint64
+	// Over is set if the first target alone doesn't fit the limits, it is visited anyway.
+	Over bool
+}
+
+// Stops returns the nodes of route visited in the session, followed by End if it's a detour.
+// route holds systems or stations, the slice returned is always new so appending to it leaves route alone.
+func (s Session) Stops(route []uint32) []uint32 {
+	stops := make([]uint32, 0, len(s.Targets)+1)
+	for _, i := range s.Targets {
+		stops = append(stops, route[i])
+	}
+	if s.Detour > 0 {
+		stops = append(stops, s.End)
+	}
+	return stops
+}
+
+// Safe reports the systems a session can end in: those with an NPC station and the extra ones listed.
+func Safe(g universe.Graph, extra map[uint32]struct{}) func(id uint32) bool {
+	return func(id uint32) bool {
+		if _, ok := extra[id]; ok {
+			return true
+		}
+		return len(g.Nodes[id].Stations) > 0
+	}
+}
+
+// sessionCosts measures legs in jumps and, if times isn't nil, in seconds.
+type sessionCosts[T distance.Weight] struct {
+	g     universe.Graph
+	times *distance.Matrix[T]
+}
+
+func (c sessionCosts[T]) leg(from, to uint32) (jumps int, seconds uint64) {
+	i, j := c.g.IdsToMatrixIndexes[from], c.g.IdsToMatrixIndexes[to]
+	jumps = int(c.g.Matrix.At(i, j))
+	if c.times != nil {
+		seconds = uint64(c.times.At(i, j))
+	}
+	return jumps, seconds
+}
+
+func (l SessionLimits) fit(jumps int, seconds uint64) bool {
+	return (l.Jumps == 0 || jumps <= l.Jumps) && (l.Seconds == 0 || seconds <= l.Seconds)
+}
+
+// Sessions cuts route, a list of systems in visiting order, into sessions that fit limits and end in safe systems.
+// start is where the first session begins, 0 begins it at the first target. times is the travel time matrix laid out like the graph's, nil if Seconds isn't limited.
+//
+// Targets are added to a session while the closest safe system is still within reach after them,
+// the session then ends at the safe system making the smallest detour on the way to the next target.
+func Sessions[T distance.Weight](g universe.Graph, times *distance.Matrix[T], route []uint32, start uint32, safe func(id uint32) bool, limits SessionLimits) []Session {
+	if len(route) == 0 {
+		return nil
+	}
+	costs := sessionCosts[T]{g: g, times: times}
+	var safeSystems []uint32
+	for _, id := range g.MatrixIndexesToIds {
+		if safe(id) {
+			safeSystems = append(safeSystems, id)
+		}
+	}
+	if len(safeSystems) == 0 {
+		return []Session{{Targets: indexes(len(route)), End: route[len(route)-1]}}
+	}
+
+	// nearest[i] is the cost of docking after target i, nothing if it's safe
+	type dock struct {
+		jumps   int
+		seconds uint64
+	}
+	nearest := make([]dock, len(route))
+	for i, id := range route {
+		if safe(id) {
+			continue
+		}
+		best := dock{jumps: -1}
+		for _, s := range safeSystems {
+			j, sec := costs.leg(id, s)
+			if best.jumps < 0 || times != nil && sec < best.seconds || times == nil && j < best.jumps {
+				best = dock{j, sec}
+			}
+		}
+		nearest[i] = best
+	}
+
+	var sessions []Session
+	at := start
+	for next := 0; next < len(route); {
+		var s Session
+		for next < len(route) {
+			var j int
+			var sec uint64
+			if at != 0 {
+				j, sec = costs.leg(at, route[next])
+			}
+			if !limits.fit(s.Jumps+j+nearest[next].jumps, s.Seconds+sec+nearest[next].seconds) {
+				if len(s.Targets) == 0 {
+					s.Over = true // can't be helped, go there anyway
+				} else {
+					break
+				}
+			}
+			s.Targets = append(s.Targets, next)
+			s.Jumps += j
+			s.Seconds += sec
+			at = route[next]
+			next++
+			if s.Over {
+				break
+			}
+		}
+
+		last := route[s.Targets[len(s.Targets)-1]]
+		s.End = last
+		if !safe(last) {
+			s.End = endOfSession(costs, safeSystems, last, route, next, s, limits)
+			j, sec := costs.leg(last, s.End)
+			s.Detour = j
+			s.Jumps += j
+			s.Seconds += sec
+		}
+		at = s.End
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// endOfSession picks the safe system after last, within limits, that costs the least to reach and then leave for the next target.
+// If none is within limits the closest one is picked.
+func endOfSession[T distance.Weight](costs sessionCosts[T], safeSystems []uint32, last uint32, route []uint32, next int, s Session, limits SessionLimits) uint32 {
+	var best, closest uint32
+	bestCost, closestCost := -1, -1
+	for _, id := range safeSystems {
+		j, sec := costs.leg(last, id)
+		if closestCost < 0 || j < closestCost {
+			closest, closestCost = id, j
+		}
+		if !limits.fit(s.Jumps+j, s.Seconds+sec) {
+			continue
+		}
+		cost := j
+		if next < len(route) {
+			onward, _ := costs.leg(id, route[next])
+			cost += onward
+		}
+		if bestCost < 0 || cost < bestCost {
+			best, bestCost = id, cost
+		}
+	}
+	if bestCost < 0 {
+		return closest
+	}
+	return best
+}
+
+func indexes(n int) []int {
+	is := make([]int, n)
+	for i := range is {
+		is[i] = i
+	}
+	return is
+}
@@ -0,0 +1,54 @@
+package route
+
+import (
+	"slices"
+	"testing"
+
+	"eve-tour/esitest"
+	"eve-tour/universe"
+)
+
+func TestSessionStopsLeaveRouteAlone(t *testing.T) {
+	g := esitest.Graph(t, universe.CrawlOptions{Workers: 2})
+	route := []uint32{esitest.NewCaldari, esitest.Niyabainen, esitest.Nourvukaiken, esitest.Urlen}
+	full := slices.Clone(route)
+
+	sessions := Sessions[uint8](g, nil, route, 0, Safe(g, nil), SessionLimits{Jumps: 2})
+	if len(sessions) < 2 {
+		t.Fatalf("got %d sessions, want the route split", len(sessions))
+	}
+	first := sessions[0]
+	if first.Detour == 0 || first.End != esitest.Tunttaras {
+		t.Fatalf("first session %+v, want it to end with a detour to Tunttaras", first)
+	}
+
+	stops := first.Stops(route)
+	want := []uint32{esitest.NewCaldari, esitest.Niyabainen, esitest.Tunttaras}
+	if !slices.Equal(stops, want) {
+		t.Errorf("stops = %v, want %v", stops, want)
+	}
+	_ = append(stops, esitest.Jita)
+	if !slices.Equal(route, full) {
+		t.Errorf("route changed to %v after splitting, want %v", route, full)
+	}
+}
+
+func TestSessionsCoverRoute(t *testing.T) {
+	g := esitest.Graph(t, universe.CrawlOptions{Workers: 2})
+	route := []uint32{esitest.NewCaldari, esitest.Niyabainen, esitest.Nourvukaiken, esitest.Urlen}
+	sessions := Sessions[uint8](g, nil, route, esitest.Jita, Safe(g, nil), SessionLimits{Jumps: 3})
+
+	var visited []int
+	for _, s := range sessions {
+		if !Safe(g, nil)(s.End) {
+			t.Errorf("session %+v ends in %s, which has no station", s, g.Nodes[s.End].Name)
+		}
+		if !s.Over && s.Jumps > 3 {
+			t.Errorf("session %+v is over the limit of 3 jumps", s)
+		}
+		visited = append(visited, s.Targets...)
+	}
+	if !slices.Equal(visited, indexes(len(route))) {
+		t.Errorf("sessions visit %v, want every target once in order", visited)
+	}
+}
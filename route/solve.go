@@ -0,0 +1,203 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	"eve-tour/distance"
+	"eve-tour/tsplib"
+)
+
+// Solver runs an LKH-like executable in its own directory.
+type Solver struct {
+	// Dir is the working directory of the solver, problem and tour files are written there.
+	Dir string
+	// Executable is relative to Dir.
+	Executable string
+	// ParFile is copied into Dir as graph.par, it must read graph.tsp and write output.tour.
+	ParFile string
+	// Parallel is how many instances run at once, each with its own SEED in its own directory, the best tour is kept.
+	// 0 or 1 runs a single one in Dir.
+	Parallel int
+	// Log gets the solver's output and how parallel runs went, nil discards them.
+	Log *log.Logger
+}
+
+var discard = log.New(io.Discard, "", 0)
+
+// orDiscard returns l, or a logger discarding everything if it's nil.
+func orDiscard(l *log.Logger) *log.Logger {
+	if l == nil {
+		return discard
+	}
+	return l
+}
+
+var (
+	LKH  = Solver{Dir: "LKH", Executable: "./LKH", ParFile: "graph.par"}
+	GLKH = Solver{Dir: "GLKH", Executable: "./GLKH", ParFile: "graph.par"}
+)
+
+// run solves the problem written by writeProblem, starting from initialTour if it isn't nil.
+// initialTour lists the zero-indexed nodes of the written problem, fake ones included.
+func (s Solver) run(ctx context.Context, writeProblem func(path string) error, initialTour []int) error {
+	var err error
+	if initialTour == nil {
+		err = copyFile(s.ParFile, filepath.Join(s.Dir, "graph.par"))
+		if err != nil {
+			return fmt.Errorf("copying graph.par: %w", err)
+		}
+	} else {
+		err = s.seed(initialTour)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeProblem(filepath.Join(s.Dir, "graph.tsp"))
+	if err != nil {
+		return fmt.Errorf("writing problem: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, s.Executable, "graph.par")
+	cmd.Dir = s.Dir
+	cmd.Stdout = orDiscard(s.Log).Writer()
+	cmd.Stderr = orDiscard(s.Log).Writer()
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("running %s: %w", s.Executable, err)
+	}
+
+	return nil
+}
+
+// seed writes initialTour next to graph.par and points LKH's INITIAL_TOUR_FILE at it.
+func (s Solver) seed(initialTour []int) error {
+	params, err := tsplib.ReadParamsFile(s.ParFile)
+	if err != nil {
+		return fmt.Errorf("reading graph.par: %w", err)
+	}
+	params.Set("INITIAL_TOUR_FILE", "initial.tour")
+	err = params.WriteFile(filepath.Join(s.Dir, "graph.par"))
+	if err != nil {
+		return fmt.Errorf("writing graph.par: %w", err)
+	}
+	t := &tsplib.Tour{Name: "initial", Nodes: initialTour}
+	err = t.WriteFile(filepath.Join(s.Dir, "initial.tour"))
+	if err != nil {
+		return fmt.Errorf("writing initial tour: %w", err)
+	}
+	return nil
+}
+
+// solve runs s on the problem written by writeProblem and returns the tour read from its output.
+// read fails on tours that aren't solutions, the cost it returns picks the best tour of parallel runs.
+func solve[R any](ctx context.Context, s Solver, writeProblem func(path string) error, initialTour []int, read func(path string) (R, uint64, error)) (R, error) {
+	if s.Parallel > 1 {
+		return solveParallel(ctx, s, writeProblem, initialTour, read)
+	}
+	err := s.run(ctx, writeProblem, initialTour)
+	if err != nil {
+		var zero R
+		return zero, err
+	}
+	r, _, err := read(filepath.Join(s.Dir, "output.tour"))
+	return r, err
+}
+
+// SolveGTSP visits one system of each bucket and returns the problem narrowed down to the chosen systems, in tour order.
+func SolveGTSP[T distance.Weight](ctx context.Context, s Solver, p Problem[T], buckets [][]uint) (Problem[T], error) {
+	order, err := solve(ctx, s, func(path string) error {
+		return tsplib.WriteGTSPFile(path, p.Matrix, buckets)
+	}, nil, func(path string) ([]uint, uint64, error) {
+		order, err := tsplib.ReadTourFile(path, false)
+		if err != nil {
+			return nil, 0, fmt.Errorf("loading solution: %w", err)
+		}
+		err = validateGTSP(order, len(p.Systems), buckets)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid solution: %w", err)
+		}
+		if len(order) == 0 {
+			return order, 0, nil
+		}
+		return order, p.PathCost(append(slices.Clone(order), order[0]), nil), nil // back to the first, GLKH closes the tour
+	})
+	if err != nil {
+		return Problem[T]{}, err
+	}
+
+	return p.Narrow(order), nil
+}
+
+// SolveSOP finds the shortest path through every system of the problem and returns it as system IDs.
+// firstHopCosts are the costs from the current location, see [Problem.FirstHopCosts], nil lets the path start anywhere.
+// precedences order some nodes, see [Problem.Precedences].
+// initial is nil or an order of every node to start from, like a previous solution, see [Problem.Seed].
+func SolveSOP[T distance.Weight](ctx context.Context, s Solver, p Problem[T], firstHopCosts []T, precedences []tsplib.Precedence, initial []uint) ([]uint32, error) {
+	order, err := SolveSOPOrder(ctx, s, p, firstHopCosts, precedences, initial)
+	if err != nil {
+		return nil, err
+	}
+
+	solution := make([]uint32, len(order))
+	for i, v := range order {
+		solution[i] = p.Systems[v]
+	}
+	return solution, nil
+}
+
+// SolveSOPOrder is [SolveSOP] returning problem indexes, for station problems or to narrow p with.
+func SolveSOPOrder[T distance.Weight](ctx context.Context, s Solver, p Problem[T], firstHopCosts []T, precedences []tsplib.Precedence, initial []uint) ([]uint, error) {
+	var initialTour []int
+	if initial != nil {
+		// between the fake start and end nodes, see [tsplib.WriteSOP]
+		initialTour = make([]int, 0, len(initial)+2)
+		initialTour = append(initialTour, 0)
+		for _, n := range initial {
+			initialTour = append(initialTour, int(n)+1)
+		}
+		initialTour = append(initialTour, len(initial)+1)
+	}
+	return solve(ctx, s, func(path string) error {
+		return tsplib.WriteSOPFile(path, p.Matrix, firstHopCosts, precedences)
+	}, initialTour, func(path string) ([]uint, uint64, error) {
+		order, err := tsplib.ReadTourFile(path, true)
+		if err != nil {
+			return nil, 0, fmt.Errorf("loading solution: %w", err)
+		}
+		err = p.Validate(order)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid solution: %w", err)
+		}
+		return order, p.PathCost(order, firstHopCosts), nil
+	})
+}
+
+func copyFile(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("opening src file: %w", err)
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("creating dst file: %w", err)
+	}
+	defer dstFile.Close()
+
+	_, err = io.CopyBuffer(dstFile, srcFile, make([]byte, 1024*1024*32))
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("copying: %w", err)
+	}
+
+	return nil
+}
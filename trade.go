@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+
+	"eve-tour/courier"
+	"eve-tour/esi"
+	"eve-tour/market"
+	"eve-tour/route"
+	"eve-tour/universe"
+)
+
+const tradeUsage = `usage: eve-tour trade [-orders FILE | -regions NAMES] [-capacity M3] [-wallet ISK]
+
+Finds items selling for less than they fetch elsewhere and plans the buying and selling stops earning the most ISK per jump, then uploads the route.
+Orders are read from a dump of ESI's market orders or downloaded from the markets of regions.
+`
+
+func runTrade(args []string) error {
+	fs := flag.NewFlagSet("trade", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), tradeUsage)
+		fs.PrintDefaults()
+	}
+	var ordersFile string
+	fs.StringVar(&ordersFile, "orders", "", "JSON dump of market orders as returned by ESI's /markets/{region}/orders, pages may be concatenated.")
+	var regions string
+	fs.StringVar(&regions, "regions", "", "Download the market orders of theses regions, separated by commas. Trades between regions need all of them.")
+	var limits courier.Limits
+	fs.Float64Var(&limits.Capacity, "capacity", 0, "Cargo capacity in m³, 0 means no limit.")
+	fs.Float64Var(&limits.Collateral, "wallet", 0, "ISK available to buy with, 0 means no limit.")
+	fs.IntVar(&limits.MaxJumps, "max-jumps", 0, "Longest tour in jumps, 0 means no limit.")
+	var fees market.Fees
+	fs.Float64Var(&fees.SalesTax, "sales-tax", 0.075, "Sales tax, the untrained rate by default, lower it for Accounting.")
+	fs.Float64Var(&fees.BrokerFee, "broker-fee", 0.03, "Broker fee of -list, the untrained NPC station rate by default.")
+	fs.BoolVar(&fees.List, "list", false, "Sell by undercutting the cheapest sell order of the destination rather than to its buy orders.")
+	var countCostOfStartSystem bool
+	fs.BoolVar(&countCostOfStartSystem, "start", false, "Start from your current system.")
+	client := esi.NewClient()
+	fs.StringVar(&client.BaseURL, "esi-url", client.BaseURL, "Base URL of the ESI API.")
+	fs.StringVar(&client.SSOURL, "sso-url", client.SSOURL, "Base URL of the EVE SSO.")
+	fs.Parse(args)
+	if (ordersFile == "") == (regions == "") {
+		fs.Usage()
+		return fmt.Errorf("need one of -orders or -regions")
+	}
+
+	g, err := universe.LoadOrCreate(client, universe.CrawlOptions{Workers: 8, Log: logger})
+	if err != nil {
+		return fmt.Errorf("failed to load graph: %w", err)
+	}
+	structures, ok, err := universe.LoadStructures(universe.StructuresFile)
+	if err != nil {
+		return err
+	}
+	if ok {
+		g.AddStructures(structures)
+	}
+
+	var orders []esi.MarketOrder
+	if ordersFile != "" {
+		orders, err = market.ReadOrders(ordersFile)
+		if err != nil {
+			return err
+		}
+	} else {
+		ids, err := lookupRegions(client, route.ParseNames(regions))
+		if err != nil {
+			return err
+		}
+		for _, id := range slices.Sorted(maps.Keys(ids)) {
+			o, err := client.MarketOrders(id)
+			if err != nil {
+				return fmt.Errorf("fetching orders of %s: %w", ids[id], err)
+			}
+			orders = append(orders, o...)
+		}
+	}
+
+	types, err := market.LoadTypes(market.TypesFile)
+	if err != nil {
+		return err
+	}
+	fetched, err := market.FetchTypes(client, types, market.Candidates(orders, fees))
+	if fetched {
+		// keep what we got even if a type failed
+		if err := market.SaveTypes(market.TypesFile, types); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	deals := market.Deals(g, orders, types, fees, limits)
+	fmt.Println(len(orders), "orders,", len(deals), "profitable deals")
+
+	var token string
+	var start uint32
+	if countCostOfStartSystem {
+		token, start, err = currentSystem(client)
+		if err != nil {
+			return err
+		}
+	}
+
+	plan := courier.Solve(g, market.Contracts(deals), start, limits)
+	if len(plan.Stops) == 0 {
+		return fmt.Errorf("no deal fits the limits")
+	}
+	var systems []uint32
+	for _, s := range plan.Stops {
+		d := deals[s.Contract]
+		name := types[d.Type].Name
+		system := d.To
+		if s.Pickup {
+			system = d.From
+			fmt.Printf("buy  %d %s\tin %s for %s ISK\t%.0f m³\n", d.Quantity, name, g.Nodes[d.From].Name, isk(d.Cost), d.Volume)
+		} else {
+			fmt.Printf("sell %d %s\tin %s for %s ISK profit\n", d.Quantity, name, g.Nodes[d.To].Name, isk(d.Profit))
+		}
+		if len(systems) == 0 || systems[len(systems)-1] != system {
+			systems = append(systems, system)
+		}
+	}
+	fmt.Printf("%d deals, %d jumps, %s ISK profit, %s ISK per jump\n", len(plan.Contracts()), plan.Jumps, isk(plan.Reward), isk(plan.IskPerJump()))
+
+	return uploadStops(client, token, g, systems)
+}
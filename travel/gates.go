@@ -0,0 +1,129 @@
+package travel
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"eve-tour/distance"
+	"eve-tour/universe"
+)
+
+// GateGraph is the stargate level graph: nodes are stargates, edges are jumps and the warps between gates of the same system.
+// A node means "just arrived on grid with this gate", so leaving by the gate you came from doesn't cost a warp.
+type GateGraph struct {
+	// Gates maps node indexes to stargate IDs.
+	Gates   []uint32
+	Indexes map[uint32]uint
+	// Adjacency costs are in tenths of seconds, rounding each edge to whole seconds would add up over long routes.
+	Adjacency [][]distance.Edge[uint32]
+}
+
+// NewGateGraph builds the gate graph of the reachable systems.
+func NewGateGraph(g universe.Graph, p ShipProfile) (GateGraph, error) {
+	if len(g.Stargates) == 0 {
+		return GateGraph{}, fmt.Errorf("%s has no stargate positions, delete it to download them", universe.GraphFile)
+	}
+
+	var gg GateGraph
+	gg.Indexes = make(map[uint32]uint)
+	for _, id := range g.MatrixIndexesToIds {
+		for _, gate := range g.Nodes[id].Stargates {
+			if _, ok := g.Reachable[g.Stargates[gate].Destination]; !ok {
+				continue
+			}
+			gg.Indexes[gate] = uint(len(gg.Gates))
+			gg.Gates = append(gg.Gates, gate)
+		}
+	}
+
+	deciseconds := func(seconds float64) uint32 {
+		return uint32(max(math.Ceil(seconds*10), 1))
+	}
+	gg.Adjacency = make([][]distance.Edge[uint32], len(gg.Gates))
+	for i, id := range gg.Gates {
+		gate := g.Stargates[id]
+		if to, ok := gg.Indexes[gate.DestinationGate]; ok {
+			gg.Adjacency[i] = append(gg.Adjacency[i], distance.Edge[uint32]{To: to, Cost: deciseconds(JumpTime)})
+		}
+		for _, otherID := range g.Nodes[gate.SystemID].Stargates {
+			to, ok := gg.Indexes[otherID]
+			if !ok || otherID == id {
+				continue
+			}
+			cost := p.GateToGate(gate.Position, g.Stargates[otherID].Position)
+			gg.Adjacency[i] = append(gg.Adjacency[i], distance.Edge[uint32]{To: to, Cost: deciseconds(cost)})
+		}
+	}
+	return gg, nil
+}
+
+// SystemMatrix projects the gate graph back to seconds between systems, laid out like g.Matrix.
+// The cost from X to Y is the quickest way to arrive in Y, averaged over the gates you may have entered X from.
+func (gg GateGraph) SystemMatrix(g universe.Graph) distance.Matrix[uint16] {
+	n := uint(len(g.MatrixIndexesToIds))
+	m := distance.New[uint16](n)
+
+	// systemOf maps gate nodes to matrix indexes.
+	systemOf := make([]uint, len(gg.Gates))
+	for i, id := range gg.Gates {
+		systemOf[i] = g.IdsToMatrixIndexes[g.Stargates[id].SystemID]
+	}
+
+	const unreachable = math.MaxUint64 / 2 // saturating there keeps the sums from overflowing
+	rows := make(chan uint)
+	var wg sync.WaitGroup
+	for range runtime.GOMAXPROCS(0) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dist := make([]uint64, len(gg.Gates))
+			arrival := make([]uint64, n)
+			sum := make([]uint64, n)
+			for row := range rows {
+				clear(sum)
+				var starts uint64
+				for _, gate := range g.Nodes[g.MatrixIndexesToIds[row]].Stargates {
+					start, ok := gg.Indexes[gate]
+					if !ok {
+						continue
+					}
+					starts++
+					distance.Dijkstra(gg.Adjacency, start, dist)
+					for i := range arrival {
+						arrival[i] = math.MaxUint64
+					}
+					for node, d := range dist {
+						arrival[systemOf[node]] = min(arrival[systemOf[node]], d)
+					}
+					for i, d := range arrival {
+						if d == math.MaxUint64 {
+							sum[i] = unreachable
+							continue
+						}
+						sum[i] = min(sum[i]+d, unreachable)
+					}
+				}
+
+				for col := range n {
+					switch {
+					case col == row:
+						m.Set(row, col, 0)
+					case starts == 0 || sum[col] == unreachable:
+						m.Set(row, col, distance.Max[uint16]())
+					default:
+						seconds := math.Ceil(float64(sum[col]) / float64(starts) / 10)
+						m.Set(row, col, uint16(min(seconds, math.MaxUint16-1)))
+					}
+				}
+			}
+		}()
+	}
+	for row := range n {
+		rows <- row
+	}
+	close(rows)
+	wg.Wait()
+	return m
+}
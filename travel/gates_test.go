@@ -0,0 +1,53 @@
+package travel
+
+import (
+	"math"
+	"testing"
+
+	"eve-tour/universe"
+)
+
+func TestNewGateGraph(t *testing.T) {
+	g := line()
+	p := Profiles[0]
+	gg, err := NewGateGraph(g, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// D's gate and the one leading there are left out
+	if len(gg.Gates) != 4 {
+		t.Fatalf("gates %v, want A's, B's and the one from C to B", gg.Gates)
+	}
+	warp := uint32(math.Ceil(p.GateToGate(universe.Position{}, universe.Position{X: 10 * au}) * 10))
+	want := map[[2]uint32]uint32{
+		{11, 21}: JumpTime * 10,
+		{21, 11}: JumpTime * 10,
+		{21, 22}: warp,
+		{22, 21}: warp,
+		{22, 31}: JumpTime * 10,
+		{31, 22}: JumpTime * 10,
+	}
+	edges := 0
+	for i, adjacent := range gg.Adjacency {
+		for _, e := range adjacent {
+			from, to := gg.Gates[i], gg.Gates[e.To]
+			cost, ok := want[[2]uint32{from, to}]
+			if !ok || cost != e.Cost {
+				t.Errorf("edge from gate %d to %d costs %d, want %d", from, to, e.Cost, cost)
+			}
+			edges++
+		}
+	}
+	if edges != len(want) {
+		t.Errorf("%d edges, want %d", edges, len(want))
+	}
+
+	// B to C is averaged over the gates B may have been entered from, one is next to C's
+	m := gg.SystemMatrix(g)
+	if got, want := m.At(0, 1), uint16(JumpTime); got != want {
+		t.Errorf("A to B takes %d s, want %d", got, want)
+	}
+	if got, want := m.At(1, 2), uint16(math.Ceil(float64(warp+2*JumpTime*10)/2/10)); got != want {
+		t.Errorf("B to C takes %d s, want %d", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+// Package travel estimates how long it takes to fly through the stargate graph.
+//
+// Every jump costs the session change timer, then the pilot aligns and warps from the arrival gate to the next one.
+// Since the next gate isn't known at the system level, a jump into a system is charged the mean warp to its other gates.
+package travel
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"eve-tour/distance"
+	"eve-tour/universe"
+)
+
+const au = 149597870700 // meters
+
+// JumpTime is the gate activation, the system load and the session change timer, in seconds.
+const JumpTime = 10
+
+// ShipProfile is the few attributes that matter for travel time.
+type ShipProfile struct {
+	Name string
+	// AlignTime is in seconds.
+	AlignTime float64
+	// WarpSpeed is in AU/s.
+	WarpSpeed float64
+	// SubwarpSpeed is in m/s, the ship drops out of warp once slower than half of it.
+	SubwarpSpeed float64
+}
+
+// Profiles are typical unfitted hulls, pick the one closest to what you fly.
+var Profiles = []ShipProfile{
+	{Name: "shuttle", AlignTime: 2, WarpSpeed: 8, SubwarpSpeed: 500},
+	{Name: "interceptor", AlignTime: 1.8, WarpSpeed: 8, SubwarpSpeed: 420},
+	{Name: "frigate", AlignTime: 3, WarpSpeed: 5, SubwarpSpeed: 350},
+	{Name: "destroyer", AlignTime: 4, WarpSpeed: 4.5, SubwarpSpeed: 250},
+	{Name: "cruiser", AlignTime: 6.5, WarpSpeed: 3, SubwarpSpeed: 200},
+	{Name: "battlecruiser", AlignTime: 9, WarpSpeed: 2.7, SubwarpSpeed: 150},
+	{Name: "battleship", AlignTime: 12, WarpSpeed: 2, SubwarpSpeed: 110},
+	{Name: "industrial", AlignTime: 10, WarpSpeed: 4.5, SubwarpSpeed: 140},
+	{Name: "freighter", AlignTime: 40, WarpSpeed: 1.37, SubwarpSpeed: 70},
+}
+
+// Profile returns the profile called name, the error lists the known ones.
+func Profile(name string) (ShipProfile, error) {
+	i := slices.IndexFunc(Profiles, func(p ShipProfile) bool {
+		return strings.EqualFold(p.Name, name)
+	})
+	if i < 0 {
+		names := make([]string, len(Profiles))
+		for i, p := range Profiles {
+			names[i] = p.Name
+		}
+		return ShipProfile{}, fmt.Errorf("unknown ship profile %q, known profiles: %s", name, strings.Join(names, ", "))
+	}
+	return Profiles[i], nil
+}
+
+// WarpTime is the time in warp to cover meters, in seconds.
+// It follows the warp mechanics from the EVE University wiki: exponential acceleration at WarpSpeed per second, deceleration at a third of it capped to 2.
+func (p ShipProfile) WarpTime(meters float64) float64 {
+	if meters <= 0 {
+		return 0
+	}
+	kAccel := p.WarpSpeed
+	kDecel := min(p.WarpSpeed/3, 2)
+	maxSpeed := p.WarpSpeed * au
+	dropout := min(100, p.SubwarpSpeed/2)
+
+	accelDistance := maxSpeed / kAccel
+	decelDistance := maxSpeed / kDecel
+	if accelDistance+decelDistance > meters {
+		// Short warp, the ship never reaches its top speed.
+		peak := meters * kAccel * kDecel / (kAccel + kDecel)
+		return math.Log(peak)/kAccel + math.Log(peak/dropout)/kDecel
+	}
+	cruise := (meters - accelDistance - decelDistance) / maxSpeed
+	return math.Log(maxSpeed)/kAccel + cruise + math.Log(maxSpeed/dropout)/kDecel
+}
+
+// GateToGate is the time to align and warp between two gates of the same system, in seconds.
+func (p ShipProfile) GateToGate(from, to universe.Position) float64 {
+	return p.AlignTime + p.WarpTime(from.Distance(to))
+}
+
+// JumpCost is the time of jumping through gate and flying to the next gate on the other side, in seconds.
+func (p ShipProfile) JumpCost(g universe.Graph, gate universe.Stargate) float64 {
+	arrival, ok := g.Stargates[gate.DestinationGate]
+	if !ok {
+		return JumpTime
+	}
+	var total float64
+	var count int
+	for _, next := range g.Nodes[gate.Destination].Stargates {
+		if next == gate.DestinationGate {
+			continue
+		}
+		nextGate, ok := g.Stargates[next]
+		if !ok {
+			continue
+		}
+		total += p.GateToGate(arrival.Position, nextGate.Position)
+		count++
+	}
+	if count == 0 {
+		// Dead end, we only go there to come back through the same gate.
+		return JumpTime
+	}
+	return JumpTime + total/float64(count)
+}
+
+// Matrix returns the travel time in seconds between every pair of reachable systems, laid out like g.Matrix.
+// The matrix is asymmetric since the cost of crossing a system depends on the gate you arrive from.
+func Matrix(g universe.Graph, p ShipProfile) (distance.Matrix[uint16], error) {
+	if len(g.Stargates) == 0 {
+		return distance.Matrix[uint16]{}, fmt.Errorf("%s has no stargate positions, delete it to download them", universe.GraphFile)
+	}
+
+	adjacency := make([][]distance.Edge[uint16], len(g.MatrixIndexesToIds))
+	for i, id := range g.MatrixIndexesToIds {
+		for _, gateID := range g.Nodes[id].Stargates {
+			gate := g.Stargates[gateID]
+			to, ok := g.IdsToMatrixIndexes[gate.Destination]
+			if !ok {
+				continue
+			}
+			cost := max(math.Ceil(p.JumpCost(g, gate)), 1)
+			adjacency[i] = append(adjacency[i], distance.Edge[uint16]{To: to, Cost: uint16(min(cost, math.MaxUint16-1))})
+		}
+	}
+	return distance.AllPairsSparse(adjacency), nil
+}
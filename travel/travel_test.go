@@ -0,0 +1,62 @@
+package travel
+
+import (
+	"math"
+	"testing"
+
+	"eve-tour/universe"
+)
+
+// line has A, B and C in a row, B's two gates 10 AU apart. D is off C's second gate and wasn't reached by the crawl.
+func line() universe.Graph {
+	const a, b, c, d = 1, 2, 3, 4
+	return universe.Graph{
+		Nodes: map[uint32]universe.System{
+			a: {Name: "A", Stargates: []uint32{11}},
+			b: {Name: "B", Stargates: []uint32{21, 22}},
+			c: {Name: "C", Stargates: []uint32{31, 32}},
+			d: {Name: "D", Stargates: []uint32{41}},
+		},
+		Stargates: map[uint32]universe.Stargate{
+			11: {SystemID: a, Destination: b, DestinationGate: 21},
+			21: {SystemID: b, Destination: a, DestinationGate: 11},
+			22: {SystemID: b, Destination: c, DestinationGate: 31, Position: universe.Position{X: 10 * au}},
+			31: {SystemID: c, Destination: b, DestinationGate: 22},
+			32: {SystemID: c, Destination: d, DestinationGate: 41, Position: universe.Position{Y: au}},
+			41: {SystemID: d, Destination: c, DestinationGate: 32},
+		},
+		Reachable:          map[uint32]struct{}{a: {}, b: {}, c: {}},
+		MatrixIndexesToIds: []uint32{a, b, c},
+		IdsToMatrixIndexes: map[uint32]uint{a: 0, b: 1, c: 2},
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	g := line()
+	p := Profiles[0]
+	m, err := Matrix(g, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// arriving in B is charged crossing it, A is a dead end and C only leads to D, which is off the graph
+	crossB := uint16(math.Ceil(JumpTime + p.GateToGate(universe.Position{}, universe.Position{X: 10 * au})))
+	crossC := uint16(math.Ceil(JumpTime + p.GateToGate(universe.Position{}, universe.Position{Y: au})))
+	want := [][]uint16{
+		{0, crossB, crossB + crossC},
+		{JumpTime, 0, crossC},
+		{JumpTime + crossB, crossB, 0},
+	}
+	for i, row := range want {
+		for j, cost := range row {
+			if got := m.At(uint(i), uint(j)); got != cost {
+				t.Errorf("%s to %s takes %d s, want %d", g.Nodes[g.MatrixIndexesToIds[i]].Name, g.Nodes[g.MatrixIndexesToIds[j]].Name, got, cost)
+			}
+		}
+	}
+
+	g.Stargates = nil
+	_, err = Matrix(g, p)
+	if err == nil {
+		t.Error("no error without stargate positions")
+	}
+}
@@ -0,0 +1,115 @@
+package tsplib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SyntaxError is returned for malformed files, Line is one-indexed.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return "line " + strconv.Itoa(e.Line) + ": " + e.Msg
+}
+
+// lexer reads TSPLIB files line by line for the keyword parts and token by token for the sections.
+type lexer struct {
+	s      *bufio.Scanner
+	line   int
+	tokens []string // unread tokens of the current line while inside a section
+}
+
+func newLexer(r io.Reader) *lexer {
+	s := bufio.NewScanner(bufio.NewReaderSize(r, 1024*1024))
+	s.Buffer(nil, 1024*1024*32) // FULL_MATRIX rows of the whole universe are long
+	return &lexer{s: s}
+}
+
+func (l *lexer) errorf(format string, args ...any) error {
+	return &SyntaxError{Line: l.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// nextLine returns the next non blank line.
+func (l *lexer) nextLine() (string, bool, error) {
+	if len(l.tokens) > 0 {
+		return "", false, l.errorf("unexpected %q after section", l.tokens[0])
+	}
+	for l.s.Scan() {
+		l.line++
+		line := strings.TrimSpace(l.s.Text())
+		if line != "" {
+			return line, true, nil
+		}
+	}
+	if err := l.s.Err(); err != nil {
+		return "", false, fmt.Errorf("reading: %w", err)
+	}
+	return "", false, nil
+}
+
+// keyword splits "KEY : value" lines, the colon is optional for keywords without a value.
+func keyword(line string) (key, value string) {
+	key, value, _ = strings.Cut(line, ":")
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	// Sections may start on the same line as their keyword: "TOUR_SECTION 1 2 3".
+	if first, rest, ok := strings.Cut(key, " "); ok {
+		key = first
+		value = strings.TrimSpace(rest + " " + value)
+	}
+	return strings.ToUpper(key), value
+}
+
+// startSection queues the tokens following a section keyword on the same line.
+func (l *lexer) startSection(rest string) {
+	l.tokens = strings.Fields(rest)
+}
+
+func (l *lexer) nextInt() (int, error) {
+	for len(l.tokens) == 0 {
+		if !l.s.Scan() {
+			if err := l.s.Err(); err != nil {
+				return 0, fmt.Errorf("reading: %w", err)
+			}
+			return 0, l.errorf("unexpected end of file in section")
+		}
+		l.line++
+		l.tokens = strings.Fields(l.s.Text())
+	}
+	tok := l.tokens[0]
+	l.tokens = l.tokens[1:]
+	v, err := strconv.Atoi(tok)
+	if err != nil {
+		return 0, l.errorf("expected an integer, got %q", tok)
+	}
+	return v, nil
+}
+
+// nextNode reads a one-indexed node number and returns it zero-indexed, -1 is returned as is.
+func (l *lexer) nextNode(dimension int) (int, error) {
+	v, err := l.nextInt()
+	if err != nil {
+		return 0, err
+	}
+	if v == -1 {
+		return -1, nil
+	}
+	if v < 1 || v > dimension {
+		return 0, l.errorf("node %d out of range 1..%d", v, dimension)
+	}
+	return v - 1, nil
+}
+
+func parseDimension(l *lexer, value string) (int, error) {
+	d, err := strconv.Atoi(value)
+	if err != nil || d <= 0 {
+		return 0, l.errorf("invalid DIMENSION %q", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,108 @@
+package tsplib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Param is one "KEY = value" line of an LKH parameter file, Value is empty for keywords like EOF.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params is an LKH parameter file, order is kept since later keys override earlier ones.
+type Params []Param
+
+// ParseParams reads an LKH .par file, keys are upper-cased.
+func ParseParams(r io.Reader) (Params, error) {
+	l := newLexer(r)
+	var params Params
+	for {
+		line, ok, err := l.nextLine()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if key == "" || strings.ContainsFunc(key, func(r rune) bool {
+			return !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_')
+		}) {
+			return nil, l.errorf("invalid parameter %q", line)
+		}
+		params = append(params, Param{Key: key, Value: value})
+		if key == "EOF" {
+			break
+		}
+	}
+	return params, nil
+}
+
+// Get returns the last value of key.
+func (p Params) Get(key string) (string, bool) {
+	key = strings.ToUpper(key)
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i].Key == key {
+			return p[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// Set replaces every value of key, or appends it before a trailing EOF.
+func (p *Params) Set(key, value string) {
+	key = strings.ToUpper(key)
+	var found bool
+	for i, param := range *p {
+		if param.Key == key {
+			(*p)[i].Value = value
+			found = true
+		}
+	}
+	if found {
+		return
+	}
+	at := len(*p)
+	if at > 0 && (*p)[at-1].Key == "EOF" {
+		at--
+	}
+	*p = slices.Insert(*p, at, Param{Key: key, Value: value})
+}
+
+func (p Params) Write(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	for _, param := range p {
+		if param.Value == "" {
+			fmt.Fprintf(w, "%s\n", param.Key)
+			continue
+		}
+		fmt.Fprintf(w, "%s = %s\n", param.Key, param.Value)
+	}
+	err := w.Flush()
+	if err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+	return nil
+}
+
+func ReadParamsFile(filepath string) (Params, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("opening parameter file: %w", err)
+	}
+	defer f.Close()
+
+	return ParseParams(f)
+}
+
+func (p Params) WriteFile(filepath string) error {
+	return writeFile(filepath, p.Write)
+}
@@ -0,0 +1,83 @@
+package tsplib
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const par = `PROBLEM_FILE = graph.tsp
+move_type = 5
+RUNS = 1
+SEED = 7
+RUNS = 2
+EOF
+`
+
+func TestParseParams(t *testing.T) {
+	p, err := ParseParams(strings.NewReader(par))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := p.Get("move_type"); !ok || v != "5" {
+		t.Errorf("MOVE_TYPE = %q, %v, want 5 with keys upper-cased", v, ok)
+	}
+	if v, _ := p.Get("RUNS"); v != "2" {
+		t.Errorf("RUNS = %q, want the last value 2", v)
+	}
+	if _, ok := p.Get("TOUR_FILE"); ok {
+		t.Error("found a TOUR_FILE that isn't there")
+	}
+}
+
+func TestParamsSet(t *testing.T) {
+	p, err := ParseParams(strings.NewReader(par))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Set("runs", "3")
+	p.Set("TOUR_FILE", "output.tour")
+
+	var b bytes.Buffer
+	err = p.Write(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `PROBLEM_FILE = graph.tsp
+MOVE_TYPE = 5
+RUNS = 3
+SEED = 7
+RUNS = 3
+TOUR_FILE = output.tour
+EOF
+`
+	if b.String() != want {
+		t.Errorf("wrote\n%s\nwant\n%s", b.String(), want)
+	}
+
+	got, err := ParseParams(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, p) {
+		t.Errorf("read back %v, want %v", got, p)
+	}
+}
+
+func TestParseParamsStopsAtEOF(t *testing.T) {
+	p, err := ParseParams(strings.NewReader("RUNS = 1\nEOF\nnot a parameter\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 2 {
+		t.Errorf("got %v, want RUNS and EOF", p)
+	}
+}
+
+func TestParseParamsError(t *testing.T) {
+	_, err := ParseParams(strings.NewReader("RUNS = 1\n\nMOVE TYPE = 5\n"))
+	if err == nil || err.Error() != `line 3: invalid parameter "MOVE TYPE = 5"` {
+		t.Errorf("got %v, want the bad line reported", err)
+	}
+}
@@ -0,0 +1,449 @@
+package tsplib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Problem types understood by [Parse] and [Problem.Write].
+const (
+	TSP   = "TSP"
+	ATSP  = "ATSP"
+	SOP   = "SOP"
+	GTSP  = "GTSP"
+	AGTSP = "AGTSP"
+	CVRP  = "CVRP"
+)
+
+// Edge weight formats, only explicit weights are supported.
+// The column formats of symmetric matrices list the same numbers as the opposite row format.
+const (
+	FullMatrix   = "FULL_MATRIX"
+	UpperRow     = "UPPER_ROW"
+	LowerRow     = "LOWER_ROW"
+	UpperDiagRow = "UPPER_DIAG_ROW"
+	LowerDiagRow = "LOWER_DIAG_ROW"
+	UpperCol     = "UPPER_COL"
+	LowerCol     = "LOWER_COL"
+	UpperDiagCol = "UPPER_DIAG_COL"
+	LowerDiagCol = "LOWER_DIAG_COL"
+)
+
+// Problem is a TSPLIB problem with explicit edge weights.
+// Node indexes are zero-indexed, the one-indexing of the file format is handled when reading and writing.
+type Problem struct {
+	Name     string
+	Comments []string
+	Type     string
+	// Dimension is the number of nodes, Weights is Dimension×Dimension row-major.
+	// In SOP problems -1 at (i, j) means j must come before i.
+	Dimension int
+	Weights   []int
+	// EdgeWeightFormat is used by Write, Parse sets it to the format of the file.
+	EdgeWeightFormat string
+
+	// GTSP and AGTSP: the sets of nodes, the tour visits one node of each.
+	Sets [][]int
+
+	// CVRP
+	Capacity int
+	Demands  []int
+	Depots   []int
+}
+
+// NewProblem returns a problem of the given type with zeroed weights and a FULL_MATRIX format.
+func NewProblem(typ string, dimension int) *Problem {
+	return &Problem{
+		Type:             typ,
+		Dimension:        dimension,
+		Weights:          make([]int, dimension*dimension),
+		EdgeWeightFormat: FullMatrix,
+	}
+}
+
+func (p *Problem) At(i, j int) int {
+	return p.Weights[i*p.Dimension+j]
+}
+
+func (p *Problem) Set(i, j, w int) {
+	p.Weights[i*p.Dimension+j] = w
+}
+
+func (p *Problem) symmetric() bool {
+	for i := range p.Dimension {
+		for j := range i {
+			if p.At(i, j) != p.At(j, i) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// weightOrder calls f with the coordinates of each weight, in the order format lists them.
+func weightOrder(format string, dimension int, f func(i, j int) error) error {
+	upper := func(diag bool) error {
+		for i := range dimension {
+			start := i + 1
+			if diag {
+				start = i
+			}
+			for j := start; j < dimension; j++ {
+				if err := f(i, j); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	lower := func(diag bool) error {
+		for i := range dimension {
+			end := i
+			if diag {
+				end = i + 1
+			}
+			for j := range end {
+				if err := f(i, j); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	switch format {
+	case FullMatrix:
+		for i := range dimension {
+			for j := range dimension {
+				if err := f(i, j); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case UpperRow, LowerCol:
+		return upper(false)
+	case UpperDiagRow, LowerDiagCol:
+		return upper(true)
+	case LowerRow, UpperCol:
+		return lower(false)
+	case LowerDiagRow, UpperDiagCol:
+		return lower(true)
+	default:
+		return fmt.Errorf("unsupported EDGE_WEIGHT_FORMAT %q", format)
+	}
+}
+
+func validFormat(format string) bool {
+	switch format {
+	case FullMatrix, UpperRow, LowerRow, UpperDiagRow, LowerDiagRow, UpperCol, LowerCol, UpperDiagCol, LowerDiagCol:
+		return true
+	}
+	return false
+}
+
+func validType(typ string) bool {
+	switch typ {
+	case TSP, ATSP, SOP, GTSP, AGTSP, CVRP:
+		return true
+	}
+	return false
+}
+
+// Parse reads a TSPLIB problem.
+// It is strict: unknown keywords, missing sections and out of range nodes are errors reporting the offending line.
+func Parse(r io.Reader) (*Problem, error) {
+	l := newLexer(r)
+	p := &Problem{}
+	gtspSets := -1
+	var sawWeights bool
+parse:
+	for {
+		line, ok, err := l.nextLine()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		key, value := keyword(line)
+		switch key {
+		case "NAME":
+			p.Name = value
+		case "COMMENT":
+			p.Comments = append(p.Comments, value)
+		case "TYPE":
+			p.Type = strings.ToUpper(value)
+			if !validType(p.Type) {
+				return nil, l.errorf("unsupported TYPE %q", value)
+			}
+		case "DIMENSION":
+			p.Dimension, err = parseDimension(l, value)
+			if err != nil {
+				return nil, err
+			}
+		case "CAPACITY":
+			p.Capacity, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, l.errorf("invalid CAPACITY %q", value)
+			}
+		case "GTSP_SETS":
+			gtspSets, err = strconv.Atoi(value)
+			if err != nil || gtspSets <= 0 {
+				return nil, l.errorf("invalid GTSP_SETS %q", value)
+			}
+		case "EDGE_WEIGHT_TYPE":
+			if strings.ToUpper(value) != "EXPLICIT" {
+				return nil, l.errorf("unsupported EDGE_WEIGHT_TYPE %q, only EXPLICIT is supported", value)
+			}
+		case "EDGE_WEIGHT_FORMAT":
+			p.EdgeWeightFormat = strings.ToUpper(value)
+			if !validFormat(p.EdgeWeightFormat) {
+				return nil, l.errorf("unsupported EDGE_WEIGHT_FORMAT %q", value)
+			}
+		case "EDGE_WEIGHT_SECTION":
+			if p.Dimension == 0 || p.EdgeWeightFormat == "" || p.Type == "" {
+				return nil, l.errorf("EDGE_WEIGHT_SECTION before TYPE, DIMENSION and EDGE_WEIGHT_FORMAT")
+			}
+			l.startSection(value)
+			if p.Type == SOP {
+				// SOP matrices repeat the dimension first.
+				d, err := l.nextInt()
+				if err != nil {
+					return nil, err
+				}
+				if d != p.Dimension {
+					return nil, l.errorf("SOP EDGE_WEIGHT_SECTION dimension %d does not match DIMENSION %d", d, p.Dimension)
+				}
+			}
+			symmetric := p.EdgeWeightFormat != FullMatrix
+			if symmetric && (p.Type == ATSP || p.Type == SOP || p.Type == AGTSP) {
+				return nil, l.errorf("%s problems need a FULL_MATRIX", p.Type)
+			}
+			p.Weights = make([]int, p.Dimension*p.Dimension)
+			err := weightOrder(p.EdgeWeightFormat, p.Dimension, func(i, j int) error {
+				w, err := l.nextInt()
+				if err != nil {
+					return err
+				}
+				p.Set(i, j, w)
+				if symmetric {
+					p.Set(j, i, w)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			sawWeights = true
+		case "GTSP_SET_SECTION":
+			if p.Dimension == 0 || gtspSets < 0 {
+				return nil, l.errorf("GTSP_SET_SECTION before DIMENSION and GTSP_SETS")
+			}
+			l.startSection(value)
+			p.Sets = make([][]int, gtspSets)
+			for range gtspSets {
+				id, err := l.nextInt()
+				if err != nil {
+					return nil, err
+				}
+				if id < 1 || id > gtspSets {
+					return nil, l.errorf("set %d out of range 1..%d", id, gtspSets)
+				}
+				if p.Sets[id-1] != nil {
+					return nil, l.errorf("set %d listed twice", id)
+				}
+				set := []int{}
+				for {
+					n, err := l.nextNode(p.Dimension)
+					if err != nil {
+						return nil, err
+					}
+					if n == -1 {
+						break
+					}
+					set = append(set, n)
+				}
+				p.Sets[id-1] = set
+			}
+		case "DEMAND_SECTION":
+			if p.Dimension == 0 {
+				return nil, l.errorf("DEMAND_SECTION before DIMENSION")
+			}
+			l.startSection(value)
+			p.Demands = make([]int, p.Dimension)
+			for range p.Dimension {
+				n, err := l.nextNode(p.Dimension)
+				if err != nil {
+					return nil, err
+				}
+				if n == -1 {
+					return nil, l.errorf("unexpected -1 in DEMAND_SECTION")
+				}
+				p.Demands[n], err = l.nextInt()
+				if err != nil {
+					return nil, err
+				}
+			}
+		case "DEPOT_SECTION":
+			if p.Dimension == 0 {
+				return nil, l.errorf("DEPOT_SECTION before DIMENSION")
+			}
+			l.startSection(value)
+			for {
+				n, err := l.nextNode(p.Dimension)
+				if err != nil {
+					return nil, err
+				}
+				if n == -1 {
+					break
+				}
+				p.Depots = append(p.Depots, n)
+			}
+		case "EOF":
+			break parse
+		default:
+			return nil, l.errorf("unknown keyword %q", key)
+		}
+	}
+
+	if p.Type == "" {
+		return nil, l.errorf("missing TYPE")
+	}
+	if !sawWeights {
+		return nil, l.errorf("missing EDGE_WEIGHT_SECTION")
+	}
+	if (p.Type == GTSP || p.Type == AGTSP) && p.Sets == nil {
+		return nil, l.errorf("missing GTSP_SET_SECTION")
+	}
+	if p.Type == CVRP && p.Demands == nil {
+		return nil, l.errorf("missing DEMAND_SECTION")
+	}
+	return p, nil
+}
+
+// Write writes the problem in p.EdgeWeightFormat, FULL_MATRIX if empty.
+func (p *Problem) Write(out io.Writer) error {
+	if !validType(p.Type) {
+		return fmt.Errorf("unsupported TYPE %q", p.Type)
+	}
+	if len(p.Weights) != p.Dimension*p.Dimension {
+		return fmt.Errorf("%d weights for dimension %d", len(p.Weights), p.Dimension)
+	}
+	format := p.EdgeWeightFormat
+	if format == "" {
+		format = FullMatrix
+	}
+	if format != FullMatrix && !p.symmetric() {
+		return fmt.Errorf("%s needs a symmetric matrix", format)
+	}
+	if (p.Type == GTSP || p.Type == AGTSP) && p.Sets == nil {
+		return fmt.Errorf("%s problem without sets", p.Type)
+	}
+
+	w := bufio.NewWriterSize(out, 1024*1024)
+	if p.Name != "" {
+		fmt.Fprintf(w, "NAME : %s\n", p.Name)
+	}
+	for _, c := range p.Comments {
+		fmt.Fprintf(w, "COMMENT : %s\n", c)
+	}
+	fmt.Fprintf(w, "TYPE : %s\nDIMENSION : %d\n", p.Type, p.Dimension)
+	if p.Type == CVRP {
+		fmt.Fprintf(w, "CAPACITY : %d\n", p.Capacity)
+	}
+	if p.Sets != nil {
+		fmt.Fprintf(w, "GTSP_SETS : %d\n", len(p.Sets))
+	}
+	fmt.Fprintf(w, "EDGE_WEIGHT_TYPE : EXPLICIT\nEDGE_WEIGHT_FORMAT : %s\nEDGE_WEIGHT_SECTION\n", format)
+	if p.Type == SOP {
+		fmt.Fprintf(w, "%d\n", p.Dimension)
+	}
+
+	var recycled []byte
+	row := -1
+	err := weightOrder(format, p.Dimension, func(i, j int) error {
+		if i != row {
+			if row != -1 {
+				w.WriteByte('\n')
+			}
+			row = i
+		} else {
+			w.WriteByte(' ')
+		}
+		recycled = strconv.AppendInt(recycled[:0], int64(p.At(i, j)), 10)
+		_, err := w.Write(recycled)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+	if row != -1 {
+		w.WriteByte('\n')
+	}
+
+	if p.Sets != nil {
+		w.WriteString("GTSP_SET_SECTION\n")
+		for i, set := range p.Sets {
+			recycled = strconv.AppendInt(recycled[:0], int64(i+1), 10) // LKH is one-indexed
+			for _, v := range set {
+				recycled = append(recycled, ' ')
+				recycled = strconv.AppendInt(recycled, int64(v+1), 10)
+			}
+			recycled = append(recycled, " -1\n"...)
+			w.Write(recycled)
+		}
+	}
+	if p.Demands != nil {
+		w.WriteString("DEMAND_SECTION\n")
+		for i, d := range p.Demands {
+			fmt.Fprintf(w, "%d %d\n", i+1, d)
+		}
+	}
+	if p.Depots != nil {
+		w.WriteString("DEPOT_SECTION\n")
+		for _, d := range p.Depots {
+			fmt.Fprintf(w, "%d\n", d+1)
+		}
+		w.WriteString("-1\n")
+	}
+	w.WriteString("EOF\n")
+
+	err = w.Flush()
+	if err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+	return nil
+}
+
+func ParseFile(filepath string) (*Problem, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("opening problem file: %w", err)
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+func (p *Problem) WriteFile(filepath string) error {
+	return writeFile(filepath, p.Write)
+}
+
+// Equal reports whether both problems describe the same instance, ignoring the weight format they are written in.
+func (p *Problem) Equal(o *Problem) bool {
+	return p.Name == o.Name &&
+		slices.Equal(p.Comments, o.Comments) &&
+		p.Type == o.Type &&
+		p.Dimension == o.Dimension &&
+		slices.Equal(p.Weights, o.Weights) &&
+		slices.EqualFunc(p.Sets, o.Sets, slices.Equal) &&
+		p.Capacity == o.Capacity &&
+		slices.Equal(p.Demands, o.Demands) &&
+		slices.Equal(p.Depots, o.Depots)
+}
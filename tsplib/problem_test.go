@@ -0,0 +1,279 @@
+package tsplib
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"eve-tour/distance"
+)
+
+// symmetric returns a TSP-like problem of typ with distinct weights, the same both ways.
+func symmetric(typ string, dimension int) *Problem {
+	p := NewProblem(typ, dimension)
+	for i := range dimension {
+		for j := range i {
+			p.Set(i, j, 10*i+j)
+			p.Set(j, i, 10*i+j)
+		}
+	}
+	return p
+}
+
+func asymmetric(typ string, dimension int) *Problem {
+	p := NewProblem(typ, dimension)
+	for i := range dimension {
+		for j := range dimension {
+			if i != j {
+				p.Set(i, j, 10*i+j)
+			}
+		}
+	}
+	return p
+}
+
+func TestProblemRoundTrip(t *testing.T) {
+	withFormat := func(p *Problem, format string) *Problem {
+		p.EdgeWeightFormat = format
+		return p
+	}
+	sop := asymmetric(SOP, 4)
+	sop.Set(2, 1, -1) // 1 before 2
+	gtsp := symmetric(GTSP, 5)
+	gtsp.Sets = [][]int{{0}, {1, 2}, {3, 4}}
+	agtsp := asymmetric(AGTSP, 3)
+	agtsp.Sets = [][]int{{0, 1}, {2}}
+	cvrp := symmetric(CVRP, 4)
+	cvrp.Capacity = 10
+	cvrp.Demands = []int{0, 3, 4, 5}
+	cvrp.Depots = []int{0}
+	named := symmetric(TSP, 2)
+	named.Name = "named"
+	named.Comments = []string{"first", "second : with a colon"}
+
+	tests := []struct {
+		name string
+		p    *Problem
+	}{
+		{"tsp full matrix", symmetric(TSP, 4)},
+		{"tsp upper row", withFormat(symmetric(TSP, 4), UpperRow)},
+		{"tsp lower row", withFormat(symmetric(TSP, 4), LowerRow)},
+		{"tsp upper diag row", withFormat(symmetric(TSP, 4), UpperDiagRow)},
+		{"tsp lower diag row", withFormat(symmetric(TSP, 4), LowerDiagRow)},
+		{"tsp upper col", withFormat(symmetric(TSP, 4), UpperCol)},
+		{"tsp lower diag col", withFormat(symmetric(TSP, 4), LowerDiagCol)},
+		{"tsp single node", symmetric(TSP, 1)},
+		{"name and comments", named},
+		{"atsp", asymmetric(ATSP, 4)},
+		{"sop", sop},
+		{"gtsp", gtsp},
+		{"gtsp upper row", withFormat(gtsp, UpperRow)},
+		{"agtsp", agtsp},
+		{"cvrp", cvrp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := tt.p.Write(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Parse(&b)
+			if err != nil {
+				t.Fatalf("parsing what was written: %v", err)
+			}
+			if !got.Equal(tt.p) {
+				t.Errorf("read back %+v, want %+v", got, tt.p)
+			}
+			if got.EdgeWeightFormat != tt.p.EdgeWeightFormat {
+				t.Errorf("format %s, want %s", got.EdgeWeightFormat, tt.p.EdgeWeightFormat)
+			}
+		})
+	}
+}
+
+func TestWriteRefusesAsymmetricHalfMatrix(t *testing.T) {
+	p := asymmetric(ATSP, 3)
+	p.EdgeWeightFormat = UpperRow
+	if err := p.Write(&bytes.Buffer{}); err == nil {
+		t.Error("wrote an asymmetric matrix as UPPER_ROW")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		line int
+		msg  string
+	}{
+		{
+			name: "unknown keyword",
+			in:   "NAME : x\nTYPE : TSP\nFOO : 1\n",
+			line: 3,
+			msg:  `unknown keyword "FOO"`,
+		},
+		{
+			name: "unsupported type",
+			in:   "TYPE : HCP\n",
+			line: 1,
+			msg:  `unsupported TYPE "HCP"`,
+		},
+		{
+			name: "bad dimension",
+			in:   "TYPE : TSP\n\nDIMENSION : zero\n",
+			line: 3,
+			msg:  `invalid DIMENSION "zero"`,
+		},
+		{
+			name: "weights before dimension",
+			in:   "TYPE : TSP\nEDGE_WEIGHT_FORMAT : FULL_MATRIX\nEDGE_WEIGHT_SECTION\n",
+			line: 3,
+			msg:  "EDGE_WEIGHT_SECTION before TYPE, DIMENSION and EDGE_WEIGHT_FORMAT",
+		},
+		{
+			name: "not a number",
+			in:   "TYPE : TSP\nDIMENSION : 2\nEDGE_WEIGHT_FORMAT : FULL_MATRIX\nEDGE_WEIGHT_SECTION\n0 1\n1 x\nEOF\n",
+			line: 6,
+			msg:  `expected an integer, got "x"`,
+		},
+		{
+			name: "short matrix",
+			in:   "TYPE : TSP\nDIMENSION : 2\nEDGE_WEIGHT_FORMAT : FULL_MATRIX\nEDGE_WEIGHT_SECTION\n0 1\n",
+			line: 5,
+			msg:  "unexpected end of file in section",
+		},
+		{
+			name: "long matrix",
+			in:   "TYPE : TSP\nDIMENSION : 2\nEDGE_WEIGHT_FORMAT : FULL_MATRIX\nEDGE_WEIGHT_SECTION\n0 1\n1 0 7\nEOF\n",
+			line: 6,
+			msg:  `unexpected "7" after section`,
+		},
+		{
+			name: "half matrix for atsp",
+			in:   "TYPE : ATSP\nDIMENSION : 2\nEDGE_WEIGHT_FORMAT : UPPER_ROW\nEDGE_WEIGHT_SECTION\n1\n",
+			line: 4,
+			msg:  "ATSP problems need a FULL_MATRIX",
+		},
+		{
+			name: "sop dimension mismatch",
+			in:   "TYPE : SOP\nDIMENSION : 2\nEDGE_WEIGHT_FORMAT : FULL_MATRIX\nEDGE_WEIGHT_SECTION\n3\n",
+			line: 5,
+			msg:  "SOP EDGE_WEIGHT_SECTION dimension 3 does not match DIMENSION 2",
+		},
+		{
+			name: "node out of range",
+			in: "TYPE : GTSP\nDIMENSION : 2\nGTSP_SETS : 1\nEDGE_WEIGHT_FORMAT : FULL_MATRIX\nEDGE_WEIGHT_SECTION\n0 1\n1 0\n" +
+				"GTSP_SET_SECTION\n1 1 3 -1\nEOF\n",
+			line: 9,
+			msg:  "node 3 out of range 1..2",
+		},
+		{
+			name: "set listed twice",
+			in: "TYPE : GTSP\nDIMENSION : 2\nGTSP_SETS : 2\nEDGE_WEIGHT_FORMAT : FULL_MATRIX\nEDGE_WEIGHT_SECTION\n0 1\n1 0\n" +
+				"GTSP_SET_SECTION\n1 1 -1\n1 2 -1\nEOF\n",
+			line: 10,
+			msg:  "set 1 listed twice",
+		},
+		{
+			name: "missing sets",
+			in:   "TYPE : GTSP\nDIMENSION : 1\nEDGE_WEIGHT_FORMAT : FULL_MATRIX\nEDGE_WEIGHT_SECTION\n0\nEOF\n",
+			line: 6,
+			msg:  "missing GTSP_SET_SECTION",
+		},
+		{
+			name: "missing demands",
+			in:   "TYPE : CVRP\nDIMENSION : 1\nEDGE_WEIGHT_FORMAT : FULL_MATRIX\nEDGE_WEIGHT_SECTION\n0\nEOF\n",
+			line: 6,
+			msg:  "missing DEMAND_SECTION",
+		},
+		{
+			name: "missing weights",
+			in:   "TYPE : TSP\nDIMENSION : 1\nEOF\n",
+			line: 3,
+			msg:  "missing EDGE_WEIGHT_SECTION",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.in))
+			var syntax *SyntaxError
+			if !errors.As(err, &syntax) {
+				t.Fatalf("got %v, want a syntax error", err)
+			}
+			if syntax.Line != tt.line || syntax.Msg != tt.msg {
+				t.Errorf("got %q, want line %d: %s", err, tt.line, tt.msg)
+			}
+		})
+	}
+}
+
+func TestWriteSOPParses(t *testing.T) {
+	m := distance.New[uint8](3)
+	for i := range uint(3) {
+		for j := range uint(3) {
+			if i != j {
+				m.Set(i, j, uint8(1+i+j))
+			}
+		}
+	}
+	var b bytes.Buffer
+	err := WriteSOP(&b, m, []uint8{4, 5, 6}, []Precedence{{Before: 2, After: 0}, {Before: 1, After: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Parse(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != SOP || p.Dimension != 5 {
+		t.Fatalf("got a %s of dimension %d, want a SOP of 5 with the fake start and end", p.Type, p.Dimension)
+	}
+	for i := range 3 {
+		if got := p.At(0, i+1); got != 4+i {
+			t.Errorf("start to node %d costs %d, want the first hop %d", i, got, 4+i)
+		}
+		if got := p.At(i+1, 4); got != 0 {
+			t.Errorf("node %d to end costs %d, want 0", i, got)
+		}
+		if got := p.At(i+1, 0); got != -1 {
+			t.Errorf("node %d to start costs %d, want -1 since the start comes first", i, got)
+		}
+		for j := range 3 {
+			want := int(m.At(uint(i), uint(j)))
+			if i == 0 && j == 2 || i == 2 && j == 1 {
+				want = -1
+			}
+			if got := p.At(i+1, j+1); got != want {
+				t.Errorf("(%d, %d) = %d, want %d", i, j, got, want)
+			}
+		}
+	}
+}
+
+func TestWriteGTSPParses(t *testing.T) {
+	m := distance.New[uint8](3)
+	for i := range uint(3) {
+		for j := range uint(3) {
+			if i != j {
+				m.Set(i, j, uint8(1+i+j))
+			}
+		}
+	}
+	var b bytes.Buffer
+	err := WriteGTSP(&b, m, [][]uint{{0, 2}, {1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Parse(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Sets) != 2 || len(p.Sets[0]) != 2 || p.Sets[0][0] != 0 || p.Sets[0][1] != 2 || len(p.Sets[1]) != 1 || p.Sets[1][0] != 1 {
+		t.Errorf("sets read back as %v", p.Sets)
+	}
+	if p.Dimension != 3 || p.At(0, 2) != int(m.At(0, 2)) {
+		t.Errorf("matrix read back as %v", p.Weights)
+	}
+}
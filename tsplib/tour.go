@@ -0,0 +1,176 @@
+package tsplib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Tour is a TSPLIB tour, Nodes are zero-indexed.
+type Tour struct {
+	Name      string
+	Comments  []string
+	Dimension int
+	Nodes     []int
+}
+
+// ParseTour reads a tour file, the TOUR_SECTION may start on the keyword line.
+// Nodes listed twice are an error, checking the tour against its problem is left to the caller.
+func ParseTour(r io.Reader) (*Tour, error) {
+	l := newLexer(r)
+	t := &Tour{}
+	var sawTour bool
+parse:
+	for {
+		line, ok, err := l.nextLine()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		key, value := keyword(line)
+		switch key {
+		case "NAME":
+			t.Name = value
+		case "COMMENT":
+			t.Comments = append(t.Comments, value)
+		case "TYPE":
+			if strings.ToUpper(value) != "TOUR" {
+				return nil, l.errorf("unsupported TYPE %q, expected TOUR", value)
+			}
+		case "DIMENSION":
+			t.Dimension, err = parseDimension(l, value)
+			if err != nil {
+				return nil, err
+			}
+		case "TOUR_SECTION":
+			if sawTour {
+				return nil, l.errorf("only one tour per file is supported")
+			}
+			l.startSection(value)
+			seen := make(map[int]struct{})
+			for {
+				// GLKH's DIMENSION counts sets, not nodes, so the upper bound can't be checked here.
+				n, err := l.nextNode(math.MaxInt)
+				if err != nil {
+					return nil, err
+				}
+				if n == -1 {
+					break
+				}
+				if _, ok := seen[n]; ok {
+					return nil, l.errorf("node %d visited twice", n+1)
+				}
+				seen[n] = struct{}{}
+				t.Nodes = append(t.Nodes, n)
+			}
+			sawTour = true
+		case "EOF":
+			break parse
+		default:
+			return nil, l.errorf("unknown keyword %q", key)
+		}
+	}
+
+	if !sawTour {
+		return nil, l.errorf("TOUR_SECTION not found")
+	}
+	return t, nil
+}
+
+func (t *Tour) Write(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	if t.Name != "" {
+		fmt.Fprintf(w, "NAME : %s\n", t.Name)
+	}
+	for _, c := range t.Comments {
+		fmt.Fprintf(w, "COMMENT : %s\n", c)
+	}
+	w.WriteString("TYPE : TOUR\n")
+	dimension := t.Dimension
+	if dimension == 0 {
+		dimension = len(t.Nodes)
+	}
+	fmt.Fprintf(w, "DIMENSION : %d\nTOUR_SECTION\n", dimension)
+	var recycled []byte
+	for _, n := range t.Nodes {
+		recycled = strconv.AppendInt(recycled[:0], int64(n+1), 10) // LKH is one-indexed
+		recycled = append(recycled, '\n')
+		w.Write(recycled)
+	}
+	w.WriteString("-1\nEOF\n")
+
+	err := w.Flush()
+	if err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+	return nil
+}
+
+func (t *Tour) WriteFile(filepath string) error {
+	return writeFile(filepath, t.Write)
+}
+
+// ReadTour reads the zero-indexed node order of a tour file.
+// For tours of problems written by [WriteSOP] the fake start and end nodes are checked and removed.
+func ReadTour(r io.Reader, isSop bool) ([]uint, error) {
+	t, err := ParseTour(r)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := t.Nodes
+	if isSop {
+		err = checkSOP(t)
+		if err != nil {
+			return nil, err
+		}
+		// Remove the fake start and end nodes.
+		nodes = nodes[1 : len(nodes)-1]
+	}
+
+	solution := make([]uint, len(nodes))
+	for i, n := range nodes {
+		if isSop {
+			n-- // SOP has a fake start node
+		}
+		solution[i] = uint(n)
+	}
+	return solution, nil
+}
+
+// checkSOP makes sure a SOP tour is a permutation going from the fake start node to the fake end node.
+// Nodes were checked against duplicates by [ParseTour], in range they can only all be there.
+func checkSOP(t *Tour) error {
+	n := len(t.Nodes)
+	if n < 2 {
+		return fmt.Errorf("SOP tour with %d nodes is missing its fake start and end", n)
+	}
+	if t.Dimension != 0 && t.Dimension != n {
+		return fmt.Errorf("SOP tour visits %d nodes of %d", n, t.Dimension)
+	}
+	for _, v := range t.Nodes {
+		if v >= n {
+			return fmt.Errorf("SOP tour of %d nodes visits node %d", n, v+1)
+		}
+	}
+	if t.Nodes[0] != 0 || t.Nodes[n-1] != n-1 {
+		return fmt.Errorf("SOP tour goes from node %d to node %d instead of the fake start 1 and end %d", t.Nodes[0]+1, t.Nodes[n-1]+1, n)
+	}
+	return nil
+}
+
+func ReadTourFile(filepath string, isSop bool) ([]uint, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("opening solution file: %w", err)
+	}
+	defer f.Close()
+
+	return ReadTour(f, isSop)
+}
@@ -0,0 +1,131 @@
+package tsplib
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// lkhTour is a tour as LKH writes it for a SOP problem of 3 nodes written by WriteSOP.
+const lkhTour = `NAME : graph.12.tour
+COMMENT : Length = 12
+COMMENT : Found by LKH-3 [Keld Helsgaun] Sun Oct 18 12:00:00 2026
+TYPE : TOUR
+DIMENSION : 5
+TOUR_SECTION
+1
+3
+2
+4
+5
+-1
+EOF
+`
+
+func TestParseLKHTour(t *testing.T) {
+	tour, err := ParseTour(strings.NewReader(lkhTour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tour.Name != "graph.12.tour" || tour.Dimension != 5 || len(tour.Comments) != 2 || tour.Comments[0] != "Length = 12" {
+		t.Errorf("header read as %+v", tour)
+	}
+	if want := []int{0, 2, 1, 3, 4}; !slices.Equal(tour.Nodes, want) {
+		t.Errorf("nodes %v, want %v", tour.Nodes, want)
+	}
+}
+
+func TestReadTour(t *testing.T) {
+	got, err := ReadTour(strings.NewReader(lkhTour), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint{1, 0, 2}; !slices.Equal(got, want) {
+		t.Errorf("SOP order %v, want %v without the fake start and end", got, want)
+	}
+
+	got, err = ReadTour(strings.NewReader(lkhTour), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint{0, 2, 1, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("order %v, want %v", got, want)
+	}
+}
+
+func TestTourRoundTrip(t *testing.T) {
+	tour := &Tour{Name: "t", Comments: []string{"c"}, Dimension: 4, Nodes: []int{3, 1, 0, 2}}
+	var b bytes.Buffer
+	err := tour.Write(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseTour(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != tour.Name || !slices.Equal(got.Comments, tour.Comments) || got.Dimension != tour.Dimension || !slices.Equal(got.Nodes, tour.Nodes) {
+		t.Errorf("read back %+v, want %+v", got, tour)
+	}
+}
+
+func TestTourSectionOnKeywordLine(t *testing.T) {
+	tour, err := ParseTour(strings.NewReader("TOUR_SECTION 2 1\n3 -1\nEOF\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 0, 2}; !slices.Equal(tour.Nodes, want) {
+		t.Errorf("nodes %v, want %v", tour.Nodes, want)
+	}
+}
+
+func TestParseTourErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		line int
+		msg  string
+	}{
+		{"wrong type", "TYPE : TSP\n", 1, `unsupported TYPE "TSP", expected TOUR`},
+		{"duplicate node", "TOUR_SECTION\n1\n2\n1\n-1\n", 4, "node 1 visited twice"},
+		{"zero node", "TOUR_SECTION\n1\n0\n-1\n", 3, "node 0 out of range 1..9223372036854775807"},
+		{"no terminator", "TOUR_SECTION\n1\n2\n", 3, "unexpected end of file in section"},
+		{"two tours", "TOUR_SECTION\n1 -1\nTOUR_SECTION\n1 -1\n", 3, "only one tour per file is supported"},
+		{"no tour", "NAME : x\nEOF\n", 2, "TOUR_SECTION not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTour(strings.NewReader(tt.in))
+			var syntax *SyntaxError
+			if !errors.As(err, &syntax) {
+				t.Fatalf("got %v, want a syntax error", err)
+			}
+			if syntax.Line != tt.line || syntax.Msg != tt.msg {
+				t.Errorf("got %q, want line %d: %s", err, tt.line, tt.msg)
+			}
+		})
+	}
+}
+
+func TestReadTourChecksSOP(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"too short", "TOUR_SECTION 1 -1\n"},
+		{"dimension mismatch", "DIMENSION : 5\nTOUR_SECTION 1 2 3 4 -1\n"},
+		{"node out of range", "TOUR_SECTION 1 2 7 4 -1\n"},
+		{"fake start elsewhere", "TOUR_SECTION 2 1 3 4 -1\n"},
+		{"fake end elsewhere", "TOUR_SECTION 1 4 3 2 -1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadTour(strings.NewReader(tt.in), true)
+			if err == nil {
+				t.Error("accepted a malformed SOP tour")
+			}
+		})
+	}
+}
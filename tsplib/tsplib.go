@@ -0,0 +1,221 @@
+// Package tsplib reads and writes the TSPLIB files consumed and produced by LKH and GLKH.
+//
+// [Problem], [Tour] and [Params] cover the general formats for exchanging files with other tools.
+// [WriteGTSP] and [WriteSOP] stream a [distance.Matrix] straight to disk, the full universe is too big to go through [Problem].
+package tsplib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"eve-tour/distance"
+)
+
+// WriteGTSP writes a GTSP problem, gtspBuckets are the sets of node indexes the tour must visit one of.
+func WriteGTSP[T distance.Weight](out io.Writer, distances distance.Matrix[T], gtspBuckets [][]uint) error {
+	w := bufio.NewWriterSize(out, 1024*1024*32)
+
+	_, err := fmt.Fprintf(w, `TYPE: GTSP
+GTSP_SETS: %d
+EDGE_WEIGHT_TYPE: EXPLICIT
+EDGE_WEIGHT_FORMAT: FULL_MATRIX
+DIMENSION: %d
+EDGE_WEIGHT_SECTION
+`, len(gtspBuckets), distances.RowSize)
+	if err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+
+	var recycled []byte
+	for i := range distances.RowSize {
+		for j := range distances.RowSize {
+			if j > 0 {
+				err := w.WriteByte(' ')
+				if err != nil {
+					return fmt.Errorf("writing: %w", err)
+				}
+			}
+			recycled = strconv.AppendUint(recycled[:0], uint64(distances.At(i, j)), 10)
+			_, err := w.Write(recycled)
+			if err != nil {
+				return fmt.Errorf("writing: %w", err)
+			}
+		}
+		err := w.WriteByte('\n')
+		if err != nil {
+			return fmt.Errorf("writing: %w", err)
+		}
+	}
+
+	_, err = w.WriteString("GTSP_SET_SECTION\n")
+	if err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+	for i, bucket := range gtspBuckets {
+		recycled = strconv.AppendUint(recycled[:0], uint64(i+1), 10) // LKH is one-indexed
+		_, err := w.Write(recycled)
+		if err != nil {
+			return fmt.Errorf("writing: %w", err)
+		}
+		for _, v := range bucket {
+			recycled = append(recycled[:0], ' ')
+			recycled = strconv.AppendUint(recycled, uint64(v+1), 10) // LKH is one-indexed
+			_, err := w.Write(recycled)
+			if err != nil {
+				return fmt.Errorf("writing: %w", err)
+			}
+		}
+		_, err = w.WriteString(" -1\n")
+		if err != nil {
+			return fmt.Errorf("writing: %w", err)
+		}
+	}
+
+	_, err = w.WriteString("EOF\n")
+	if err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return fmt.Errorf("flushing: %w", err)
+	}
+
+	return nil
+}
+
+// Precedence makes the tour visit node Before, at some point, ahead of node After. Nodes are zero-indexed matrix indexes.
+type Precedence struct {
+	Before, After uint
+}
+
+// WriteSOP writes the matrix as an open path problem: a fake start node is prepended and a fake end node appended.
+// firstHopCosts are the costs from the fake start to every node, nil lets the tour begin anywhere for free.
+// precedences become -1 entries, LKH needs them acyclic.
+func WriteSOP[T distance.Weight](out io.Writer, distances distance.Matrix[T], firstHopCosts []T, precedences []Precedence) error {
+	w := bufio.NewWriterSize(out, 1024*1024*32)
+
+	// In SOP files -1 at row i column j means j comes before i.
+	before := make(map[uint][]uint)
+	for _, p := range precedences {
+		if p.Before >= distances.RowSize || p.After >= distances.RowSize {
+			return fmt.Errorf("precedence %d before %d out of range", p.Before, p.After)
+		}
+		before[p.After] = append(before[p.After], p.Before)
+	}
+
+	distanceWithFakeStartAndEnd := distances.RowSize + 2
+	_, err := fmt.Fprintf(w, `TYPE: SOP
+EDGE_WEIGHT_TYPE: EXPLICIT
+EDGE_WEIGHT_FORMAT: FULL_MATRIX
+DIMENSION: %d
+EDGE_WEIGHT_SECTION
+%d
+`, distanceWithFakeStartAndEnd, distanceWithFakeStartAndEnd)
+	if err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+
+	var recycled []byte
+	if firstHopCosts == nil {
+		firstHopCosts = make([]T, distances.RowSize) // we allow the searcher to begin wherever it wants
+	}
+	_, err = w.WriteString("0 ") // zeroth's diagonal
+	if err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+	for _, v := range firstHopCosts {
+		recycled = strconv.AppendUint(recycled[:0], uint64(v), 10)
+		recycled = append(recycled, ' ')
+		_, err = w.Write(recycled)
+		if err != nil {
+			return fmt.Errorf("writing: %w", err)
+		}
+	}
+	_, err = w.WriteString("-1\n") // can't skip the whole thing straight to the end
+	if err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+
+	// mustPrecede is before[i] as a set, so rows stay linear in the number of nodes.
+	mustPrecede := make([]bool, distances.RowSize)
+	for i := range distances.RowSize {
+		_, err = w.WriteString("-1 ")
+		if err != nil {
+			return fmt.Errorf("writing: %w", err)
+		}
+		for _, j := range before[i] {
+			mustPrecede[j] = true
+		}
+		for j := range distances.RowSize {
+			if mustPrecede[j] {
+				recycled = append(recycled[:0], "-1 "...)
+			} else {
+				recycled = strconv.AppendUint(recycled[:0], uint64(distances.At(i, j)), 10)
+				recycled = append(recycled, ' ')
+			}
+			_, err = w.Write(recycled)
+			if err != nil {
+				return fmt.Errorf("writing: %w", err)
+			}
+		}
+		for _, j := range before[i] {
+			mustPrecede[j] = false
+		}
+		_, err = w.WriteString("0\n")
+		if err != nil {
+			return fmt.Errorf("writing: %w", err)
+		}
+	}
+
+	// End can go nowhere.
+	for range distanceWithFakeStartAndEnd - 1 {
+		_, err = w.WriteString("-1 ")
+		if err != nil {
+			return fmt.Errorf("writing: %w", err)
+		}
+	}
+	_, err = w.WriteString("0\n")
+	if err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+
+	_, err = w.WriteString("EOF\n")
+	if err != nil {
+		return fmt.Errorf("writing: %w", err)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return fmt.Errorf("flushing: %w", err)
+	}
+
+	return nil
+}
+
+// WriteGTSPFile writes the GTSP instance of [WriteGTSP] to a file.
+func WriteGTSPFile[T distance.Weight](filepath string, distances distance.Matrix[T], gtspBuckets [][]uint) error {
+	return writeFile(filepath, func(w io.Writer) error {
+		return WriteGTSP(w, distances, gtspBuckets)
+	})
+}
+
+// WriteSOPFile writes the SOP instance of [WriteSOP] to a file.
+func WriteSOPFile[T distance.Weight](filepath string, distances distance.Matrix[T], firstHopCosts []T, precedences []Precedence) error {
+	return writeFile(filepath, func(w io.Writer) error {
+		return WriteSOP(w, distances, firstHopCosts, precedences)
+	})
+}
+
+func writeFile(filepath string, write func(w io.Writer) error) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return fmt.Errorf("creating: %w", err)
+	}
+	defer file.Close()
+
+	return write(file)
+}
@@ -0,0 +1,287 @@
+package universe
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"eve-tour/esi"
+)
+
+// CrawlOptions configures how the universe is downloaded.
+type CrawlOptions struct {
+	OnlyHighsec bool
+	// Workers is the number of systems fetched concurrently, the request rate is capped separately by the client's limiter.
+	Workers int
+	// Log gets the crawl progress and retries, nil discards them.
+	Log *log.Logger
+}
+
+var discard = log.New(io.Discard, "", 0)
+
+// orDiscard returns l, or a logger discarding everything if it's nil.
+func orDiscard(l *log.Logger) *log.Logger {
+	if l == nil {
+		return discard
+	}
+	return l
+}
+
+// crawlProgress is shared by the crawler workers and periodically summarized to the log.
+type crawlProgress struct {
+	round     atomic.Int64
+	total     atomic.Int64
+	done      atomic.Int64
+	retries   atomic.Int64
+	stargates atomic.Int64
+	stations  atomic.Int64
+}
+
+func (p *crawlProgress) summary(budget int) string {
+	total, done := p.total.Load(), p.done.Load()
+	var percent float64
+	if total > 0 {
+		percent = float64(done) / float64(total) * 100
+	}
+	return fmt.Sprintf("crawl: round=%d systems=%d/%d (%.2f%%) stargates=%d stations=%d retries=%d error-budget=%d",
+		p.round.Load(), done, total, percent, p.stargates.Load(), p.stations.Load(), p.retries.Load(), budget)
+}
+
+// report logs the progress every interval until stop is closed.
+func (p *crawlProgress) report(log *log.Logger, limiter *esi.Limiter, interval time.Duration, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			log.Println(p.summary(limiter.Budget()))
+		case <-stop:
+			log.Println(p.summary(limiter.Budget()))
+			return
+		}
+	}
+}
+
+// crawler holds the state shared between the workers of fetchSystems.
+type crawler struct {
+	client      *esi.Client
+	onlyHighsec bool
+	progress    *crawlProgress
+	log         *log.Logger
+
+	mu             sync.Mutex
+	nodes          map[uint32]System
+	edges          map[uint32][]uint32
+	stargates      map[uint32]Stargate
+	constellations map[uint32]esi.Constellation
+	regionsToName  map[uint32]string
+}
+
+func (c *crawler) constellation(id uint32) (esi.Constellation, error) {
+	c.mu.Lock()
+	cj, ok := c.constellations[id]
+	c.mu.Unlock()
+	if ok {
+		return cj, nil
+	}
+
+	cj, err := c.client.Constellation(id)
+	if err != nil {
+		return esi.Constellation{}, fmt.Errorf("fetching constellation %d: %w", id, err)
+	}
+
+	c.mu.Lock()
+	c.constellations[id] = cj
+	c.mu.Unlock()
+	return cj, nil
+}
+
+func (c *crawler) regionName(region uint32) (string, error) {
+	c.mu.Lock()
+	name, ok := c.regionsToName[region]
+	c.mu.Unlock()
+	if ok {
+		return name, nil
+	}
+
+	r, err := c.client.Region(region)
+	if err != nil {
+		return "", fmt.Errorf("fetching region %d: %w", region, err)
+	}
+
+	c.mu.Lock()
+	c.regionsToName[region] = r.Name
+	c.mu.Unlock()
+	return r.Name, nil
+}
+
+// crawlSystem fetches one system, its stations and all of its stargates.
+// Stargates are retried until they succeed since a system with missing edges would silently corrupt the graph.
+func (c *crawler) crawlSystem(id uint32) error {
+	s, err := c.client.System(id)
+	if err != nil {
+		return fmt.Errorf("fetching system %d: %w", id, err)
+	}
+
+	if c.onlyHighsec && s.SecurityStatus < 0.5 {
+		return nil
+	}
+
+	constellation, err := c.constellation(s.ConstellationID)
+	if err != nil {
+		return err
+	}
+	regionName, err := c.regionName(constellation.RegionID)
+	if err != nil {
+		return err
+	}
+
+	stations := make([]Station, len(s.Stations))
+	for i, stationID := range s.Stations {
+		st, err := c.client.Station(stationID)
+		if err != nil {
+			return fmt.Errorf("fetching station %d: %w", stationID, err)
+		}
+		c.progress.stations.Add(1)
+		stations[i] = Station{
+			ID:                    stationID,
+			Name:                  st.Name,
+			TypeID:                st.TypeID,
+			Owner:                 st.Owner,
+			Services:              st.Services,
+			MaxDockableShipVolume: st.MaxDockableShipVolume,
+		}
+	}
+
+	var destinations []uint32
+	gates := make(map[uint32]Stargate, len(s.Stargates))
+	stargates := s.Stargates
+	for len(stargates) > 0 {
+		var failedStargates []uint32
+		for _, stargate := range stargates {
+			sg, err := c.client.Stargate(stargate)
+			if err != nil {
+				c.log.Println("failed to fetch stargate, will retry later", stargate, err)
+				c.progress.retries.Add(1)
+				failedStargates = append(failedStargates, stargate)
+				continue
+			}
+			c.progress.stargates.Add(1)
+			destinations = append(destinations, sg.Destination.SystemID)
+			gates[stargate] = Stargate{
+				SystemID:        id,
+				Destination:     sg.Destination.SystemID,
+				DestinationGate: sg.Destination.StargateID,
+				Position:        Position(sg.Position),
+			}
+		}
+		stargates = failedStargates
+	}
+
+	// Workers finish in any order, keep the graph identical between crawls.
+	slices.Sort(destinations)
+	gateIDs := slices.Sorted(maps.Keys(gates))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nodes[id] = System{
+		Name:            s.Name,
+		Region:          regionName,
+		ConstellationID: s.ConstellationID,
+		Constellation:   constellation.Name,
+		Stations:        s.Stations,
+		StationInfo:     stations,
+		SecurityStatus:  s.SecurityStatus,
+		Stargates:       gateIDs,
+	}
+	if len(destinations) > 0 {
+		c.edges[id] = destinations
+	}
+	for gateID, gate := range gates {
+		c.stargates[gateID] = gate
+	}
+	return nil
+}
+
+// Crawl downloads every system and stargate from ESI, the returned graph only has Nodes, Edges and Stargates set, see [Build].
+// Failed systems are retried in rounds until everything is fetched, the result doesn't depend on the order requests complete in.
+func Crawl(client *esi.Client, opts CrawlOptions) (Graph, error) {
+	systems, err := client.Systems()
+	if err != nil {
+		return Graph{}, err
+	}
+
+	c := &crawler{
+		client:         client,
+		onlyHighsec:    opts.OnlyHighsec,
+		progress:       new(crawlProgress),
+		log:            orDiscard(opts.Log),
+		nodes:          make(map[uint32]System),
+		edges:          make(map[uint32][]uint32),
+		stargates:      make(map[uint32]Stargate),
+		constellations: make(map[uint32]esi.Constellation),
+		regionsToName:  make(map[uint32]string),
+	}
+	stop := make(chan struct{})
+	var reporter sync.WaitGroup
+	reporter.Add(1)
+	go func() {
+		defer reporter.Done()
+		c.progress.report(c.log, client.Limiter, 5*time.Second, stop)
+	}()
+	defer func() {
+		close(stop)
+		reporter.Wait()
+	}()
+
+	workers := max(opts.Workers, 1)
+	for round := int64(1); len(systems) > 0; round++ {
+		c.progress.round.Store(round)
+		c.progress.total.Store(int64(len(systems)))
+		c.progress.done.Store(0)
+
+		jobs := make(chan uint32)
+		var failedMu sync.Mutex
+		var failedSystems []uint32
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for id := range jobs {
+					err := c.crawlSystem(id)
+					if err != nil {
+						c.log.Println("failed to fetch system, will retry later", id, err)
+						c.progress.retries.Add(1)
+						failedMu.Lock()
+						failedSystems = append(failedSystems, id)
+						failedMu.Unlock()
+					}
+					c.progress.done.Add(1)
+				}
+			}()
+		}
+		for _, id := range systems {
+			if id == ZarzakhID {
+				continue // Zarzakh is not worth it, it require to wait 6 hours to go through an other stargates you entered from.
+			}
+			jobs <- id
+		}
+		close(jobs)
+		wg.Wait()
+
+		slices.Sort(failedSystems)
+		systems = failedSystems
+	}
+
+	return Graph{
+		Nodes:     c.nodes,
+		Edges:     c.edges,
+		Stargates: c.stargates,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package universe_test
+
+import (
+	"slices"
+	"testing"
+
+	"eve-tour/esitest"
+	"eve-tour/universe"
+)
+
+func TestCrawlFixture(t *testing.T) {
+	g := esitest.Graph(t, universe.CrawlOptions{Workers: 2})
+	u := esitest.Fixture()
+	if len(g.Nodes) != len(u.Systems) {
+		t.Errorf("crawled %d systems, want %d", len(g.Nodes), len(u.Systems))
+	}
+
+	jita := g.Nodes[esitest.Jita]
+	if jita.Name != "Jita" || jita.Region != "The Forge" || jita.Constellation != "Kimotoro" {
+		t.Errorf("Jita crawled as %+v", jita)
+	}
+	if len(jita.StationInfo) != 2 || !jita.HasService("market", false) {
+		t.Errorf("Jita's stations crawled as %+v", jita.StationInfo)
+	}
+	neighbours := slices.Sorted(slices.Values(g.Edges[esitest.Jita]))
+	want := []uint32{esitest.Niyabainen, esitest.Perimeter, esitest.NewCaldari}
+	slices.Sort(want)
+	if !slices.Equal(neighbours, want) {
+		t.Errorf("Jita links to %v, want %v", neighbours, want)
+	}
+
+	jumps := func(a, b uint32) uint8 {
+		return g.Matrix.At(g.IdsToMatrixIndexes[a], g.IdsToMatrixIndexes[b])
+	}
+	if j := jumps(esitest.Jita, esitest.Nourvukaiken); j != 3 {
+		t.Errorf("Jita to Nourvukaiken is %d jumps, want 3", j)
+	}
+	if j := jumps(esitest.Urlen, esitest.Uedama); j != 3 {
+		t.Errorf("Urlen to Uedama is %d jumps, want 3", j)
+	}
+}
+
+func TestCrawlOnlyHighsec(t *testing.T) {
+	g := esitest.Graph(t, universe.CrawlOptions{Workers: 2, OnlyHighsec: true})
+	if _, ok := g.IdsToMatrixIndexes[esitest.Uedama]; ok {
+		t.Error("lowsec Uedama is in the highsec matrix")
+	}
+	if _, ok := g.IdsToMatrixIndexes[esitest.Sobaseki]; !ok {
+		t.Error("highsec Sobaseki is missing from the matrix")
+	}
+}
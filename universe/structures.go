@@ -0,0 +1,130 @@
+package universe
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"slices"
+	"sync"
+
+	"eve-tour/esi"
+)
+
+// StructuresFile caches the public structures, they change too often to live in graph.json.
+const StructuresFile = "structures.json"
+
+// Structure is a public Upwell structure.
+type Structure struct {
+	ID     uint64
+	Name   string
+	TypeID uint32
+	Owner  uint32
+	// Services only knows "market" and "manufacturing", the two ESI lets us filter public structures by.
+	Services []string
+}
+
+// structureFilters maps ESI's structure list filters to the service names stations use.
+var structureFilters = map[string]string{
+	"market":              "market",
+	"manufacturing_basic": "factory",
+}
+
+// FetchStructures downloads the public structures, the token needs the esi-universe.read_structures.v1 scope.
+// Structures the character can't see anymore are skipped with a line in log, nil keeps quiet. The result is keyed by system.
+func FetchStructures(client *esi.Client, token string, workers int, log *log.Logger) (map[uint32][]Structure, error) {
+	log = orDiscard(log)
+	ids, err := client.Structures("")
+	if err != nil {
+		return nil, fmt.Errorf("listing structures: %w", err)
+	}
+	services := make(map[uint64][]string)
+	for filter, service := range structureFilters {
+		withService, err := client.Structures(filter)
+		if err != nil {
+			return nil, fmt.Errorf("listing %s structures: %w", filter, err)
+		}
+		for _, id := range withService {
+			services[id] = append(services[id], service)
+		}
+	}
+
+	var mu sync.Mutex
+	bySystem := make(map[uint32][]Structure)
+	jobs := make(chan uint64)
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				st, err := client.Structure(token, id)
+				if err != nil {
+					log.Println("skipping structure", id, err)
+					continue
+				}
+				slices.Sort(services[id])
+				mu.Lock()
+				bySystem[st.SolarSystemID] = append(bySystem[st.SolarSystemID], Structure{
+					ID:       id,
+					Name:     st.Name,
+					TypeID:   st.TypeID,
+					Owner:    st.OwnerID,
+					Services: services[id],
+				})
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, id := range ids {
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, structures := range bySystem {
+		slices.SortFunc(structures, func(a, b Structure) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+	}
+	return bySystem, nil
+}
+
+// LoadStructures reads the structures cache, ok is false if there is none.
+func LoadStructures(fileName string) (structures map[uint32][]Structure, ok bool, err error) {
+	b, err := os.ReadFile(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("reading structures: %w", err)
+	}
+	err = json.Unmarshal(b, &structures)
+	if err != nil {
+		return nil, false, fmt.Errorf("decoding structures: %w", err)
+	}
+	return structures, true, nil
+}
+
+func SaveStructures(fileName string, structures map[uint32][]Structure) error {
+	b, err := json.Marshal(structures)
+	if err != nil {
+		return fmt.Errorf("encoding structures: %w", err)
+	}
+	err = os.WriteFile(fileName, b, 0o644)
+	if err != nil {
+		return fmt.Errorf("writing structures: %w", err)
+	}
+	return nil
+}
+
+// AddStructures attaches structures to the systems of g, replacing previous ones.
+func (g *Graph) AddStructures(structures map[uint32][]Structure) {
+	for id, s := range g.Nodes {
+		s.Structures = structures[id]
+		g.Nodes[id] = s
+	}
+}
@@ -0,0 +1,235 @@
+// Package universe models the K-space stargate graph and takes care of downloading and caching it.
+package universe
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"eve-tour/distance"
+	"eve-tour/esi"
+)
+
+const JitaID = 30000142
+const ZarzakhID = 30100000 // FIXME: maybe allow to ban pathfinding by argument instead ¿ would require fancier graph.json handling
+
+func markAllReachables(reachables map[uint32]struct{}, systems map[uint32]System, edges map[uint32][]uint32, node uint32, onlyHighsec bool) {
+	if onlyHighsec {
+		if systems[node].SecurityStatus < 0.5 {
+			return
+		}
+	}
+
+	if _, ok := reachables[node]; ok {
+		return
+	}
+	reachables[node] = struct{}{}
+
+	for _, next := range edges[node] {
+		markAllReachables(reachables, systems, edges, next, onlyHighsec)
+	}
+}
+
+type System struct {
+	Name   string
+	Region string
+	// ConstellationID and Constellation are missing from graph.json files crawled before they were recorded.
+	ConstellationID uint32 `json:",omitempty"`
+	Constellation   string `json:",omitempty"`
+	Stations        []uint32
+	// StationInfo details Stations, in the same order. It is missing from graph.json files crawled before it was recorded.
+	StationInfo    []Station `json:",omitempty"`
+	SecurityStatus float32
+	Stargates      []uint32
+	// Structures are the public Upwell structures, only set when asked for, see [FetchStructures].
+	Structures []Structure `json:",omitempty"`
+}
+
+// Station is an NPC station.
+type Station struct {
+	ID     uint32
+	Name   string
+	TypeID uint32
+	// Owner is the NPC corporation running the station.
+	Owner uint32
+	// Services are ESI's service names, like "market", "cloning" or "reprocessing-plant".
+	Services []string
+	// MaxDockableShipVolume is in m³.
+	MaxDockableShipVolume float64
+}
+
+// Station returns the details of one of the system's stations.
+func (s System) Station(id uint32) (Station, bool) {
+	for _, st := range s.StationInfo {
+		if st.ID == id {
+			return st, true
+		}
+	}
+	return Station{}, false
+}
+
+// HasService reports whether any station of the system offers service, structures included if withStructures.
+func (s System) HasService(service string, withStructures bool) bool {
+	for _, st := range s.StationInfo {
+		if slices.Contains(st.Services, service) {
+			return true
+		}
+	}
+	if withStructures {
+		for _, st := range s.Structures {
+			if slices.Contains(st.Services, service) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Position is in meters, relative to the system's star.
+type Position struct {
+	X, Y, Z float64
+}
+
+func (p Position) Distance(o Position) float64 {
+	return math.Sqrt((p.X-o.X)*(p.X-o.X) + (p.Y-o.Y)*(p.Y-o.Y) + (p.Z-o.Z)*(p.Z-o.Z))
+}
+
+type Stargate struct {
+	SystemID        uint32
+	Destination     uint32
+	DestinationGate uint32
+	Position        Position
+}
+
+// Graph is the stargate network plus the all-pairs jump count matrix of the systems reachable from Jita.
+type Graph struct {
+	Nodes map[uint32]System
+	Edges map[uint32][]uint32
+	// Stargates is missing from graph.json files crawled before positions were recorded.
+	Stargates          map[uint32]Stargate
+	Reachable          map[uint32]struct{}
+	MatrixIndexesToIds []uint32
+	IdsToMatrixIndexes map[uint32]uint
+	Matrix             distance.D2
+}
+
+const GraphFile = "graph.json"
+
+// Lookup accepts a system ID or a case-insensitive name.
+func (g Graph) Lookup(arg string) (uint32, bool) {
+	if id, err := strconv.ParseUint(arg, 10, 32); err == nil {
+		if _, ok := g.Nodes[uint32(id)]; ok {
+			return uint32(id), true
+		}
+	}
+	for id, s := range g.Nodes {
+		if strings.EqualFold(s.Name, arg) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// FileName returns where the graph is cached, highsec-only graphs have their own file.
+func FileName(onlyHighsec bool) string {
+	if onlyHighsec {
+		return "highsec-" + GraphFile
+	}
+	return GraphFile
+}
+
+// Load reads a graph saved by [Graph.Save], use [FileName] for the cached one.
+func Load(fileName string) (Graph, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return Graph{}, err
+	}
+	defer f.Close()
+
+	var g Graph
+	if err := json.NewDecoder(f).Decode(&g); err != nil {
+		return Graph{}, err
+	}
+
+	return g, nil
+}
+
+func (g *Graph) Save(fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("creating graph file: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriterSize(f, 1024*1024*32)
+	err = json.NewEncoder(w).Encode(g)
+	if err != nil {
+		return fmt.Errorf("encoding graph: %w", err)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return fmt.Errorf("flushing: %w", err)
+	}
+
+	return nil
+}
+
+// Build keeps the systems of a crawled graph reachable from Jita and computes their distance matrix.
+// Computing it takes a while on the whole universe, the progress goes to log, nil keeps quiet.
+func Build(g Graph, onlyHighsec bool, log *log.Logger) Graph {
+	reachableNodes := make(map[uint32]struct{})
+	markAllReachables(reachableNodes, g.Nodes, g.Edges, JitaID, onlyHighsec)
+
+	reachableList := make([]uint32, 0, len(reachableNodes))
+	for node := range reachableNodes {
+		reachableList = append(reachableList, node)
+	}
+	// Map order is random, keep the matrix layout stable between crawls.
+	slices.Sort(reachableList)
+	nodeMap := make(map[uint32]uint) // Map from node ID to index in the matrix
+	for i, node := range reachableList {
+		nodeMap[node] = uint(i)
+	}
+
+	g.IdsToMatrixIndexes = nodeMap
+	g.MatrixIndexesToIds = reachableList
+	g.Reachable = reachableNodes
+	g.Matrix = distance.AllPairs(reachableList, nodeMap, g.Edges, log)
+	return g
+}
+
+// LoadOrCreate loads the cached graph, or crawls ESI and caches the result if there is none.
+func LoadOrCreate(c *esi.Client, opts CrawlOptions) (Graph, error) {
+	fileName := FileName(opts.OnlyHighsec)
+	g, err := Load(fileName)
+	if err == nil {
+		for _, s := range g.Nodes {
+			if s.ConstellationID == 0 || len(s.Stations) > 0 && len(s.StationInfo) == 0 {
+				orDiscard(opts.Log).Println(fileName, "predates constellations and station services, delete it to crawl again if you filter on them")
+				break
+			}
+		}
+		return g, nil
+	}
+	orDiscard(opts.Log).Println("failed to load graph, creating a new one")
+
+	g, err = Crawl(c, opts)
+	if err != nil {
+		return Graph{}, fmt.Errorf("fetching systems: %w", err)
+	}
+
+	g = Build(g, opts.OnlyHighsec, opts.Log)
+	err = g.Save(fileName)
+	if err != nil {
+		return Graph{}, err
+	}
+
+	return g, nil
+}
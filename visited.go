@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"eve-tour/esi"
+	"eve-tour/gamelog"
+	"eve-tour/universe"
+	"eve-tour/visited"
+)
+
+const visitedUsage = `usage: eve-tour visited [-character NAME] COMMAND [ARGS]
+
+Commands:
+  list               print the visited systems
+  mark SYSTEM...     mark systems as visited, by name or ID
+  unmark SYSTEM...   forget systems
+  ingest [LOG...]    read new jumps from gamelogs, all of the character's logs in the Gamelogs directory by default
+  reset              forget everything, including which logs were read
+  track              poll the character's location through ESI until interrupted, for when there are no logs
+  import-killmails FILE...
+                     mark the systems of killmails, a JSON array or one object per line as exported from zKillboard or ESI
+  import-list FILE...
+                     mark the systems listed one per line, by name or ID
+`
+
+func runVisited(args []string) error {
+	fs := flag.NewFlagSet("visited", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), visitedUsage)
+		fs.PrintDefaults()
+	}
+	var character string
+	fs.StringVar(&character, "character", "", "Character whose visited systems are edited. Defaults to the character of the most recent gamelog.")
+	var gamelogsDir string
+	fs.StringVar(&gamelogsDir, "gamelogs", "", "EVE's Gamelogs directory.")
+	var characterID uint
+	fs.UintVar(&characterID, "character-id", 0, "Only import killmails this character is on, 0 imports every mail of the file.")
+	var interval time.Duration
+	fs.DurationVar(&interval, "interval", 30*time.Second, "How often track polls the location, ESI caches it for 5 seconds.")
+	client := esi.NewClient()
+	fs.StringVar(&client.BaseURL, "esi-url", client.BaseURL, "Base URL of the ESI API.")
+	fs.StringVar(&client.SSOURL, "sso-url", client.SSOURL, "Base URL of the EVE SSO.")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing command")
+	}
+	command, args := fs.Arg(0), fs.Args()[1:]
+
+	dir, err := findGamelogs(gamelogsDir, &character, false)
+	if err != nil {
+		return err
+	}
+
+	store, err := visited.Open(character)
+	if err != nil {
+		return err
+	}
+	if command == "reset" {
+		store.Reset()
+		return store.Save()
+	}
+
+	g, err := universe.LoadOrCreate(client, universe.CrawlOptions{Workers: 8, Log: logger})
+	if err != nil {
+		return fmt.Errorf("failed to load graph: %w", err)
+	}
+
+	switch command {
+	case "list":
+		ids := make([]uint32, 0, len(store.Systems))
+		for id := range store.Systems {
+			ids = append(ids, id)
+		}
+		slices.SortFunc(ids, func(a, b uint32) int {
+			return store.Systems[a].FirstSeen.Compare(store.Systems[b].FirstSeen)
+		})
+		for _, id := range ids {
+			v := store.Systems[id]
+			fmt.Printf("%s\t%s\t%s\n", g.Nodes[id].Name, v.LastSeen.Format("2006-01-02 15:04"), strings.Join(v.Sources, ","))
+		}
+		fmt.Printf("%d/%d reachable systems visited\n", len(store.Systems), len(g.Reachable))
+		return nil
+	case "mark", "unmark":
+		for _, arg := range args {
+			id, err := lookupSystem(g, arg)
+			if err != nil {
+				return err
+			}
+			if command == "mark" {
+				store.Mark(id, time.Now().UTC(), visited.ManualSource)
+			} else {
+				store.Unmark(id)
+			}
+		}
+	case "ingest":
+		if len(args) == 0 {
+			args, err = characterLogs(dir, character)
+			if err != nil {
+				return err
+			}
+		}
+		err = ingestLogs(g, store, args)
+		if err != nil {
+			return err
+		}
+	case "track":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		fmt.Println("tracking, press Ctrl+C to stop")
+		return store.Track(ctx, &esi.Session{Client: client}, interval, logger, func(system uint32) {
+			fmt.Println("in", g.Nodes[system].Name)
+		})
+	case "import-killmails":
+		for _, file := range args {
+			imported, skipped, err := store.ImportKillmails(g, file, uint32(characterID))
+			if err != nil {
+				return err
+			}
+			fmt.Println("imported:", file, imported, "killmails,", skipped, "skipped")
+		}
+	case "import-list":
+		for _, file := range args {
+			n, err := store.ImportList(g, file)
+			if err != nil {
+				return err
+			}
+			fmt.Println("imported:", file, n, "systems")
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	return store.Save()
+}
+
+// ingestLogs adds the new jumps of logs to the store and saves it.
+func ingestLogs(g universe.Graph, store *visited.Store, logs []string) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	p := gamelog.NewParser(g)
+	p.Warn = warnUnknownSystem
+	for _, log := range logs {
+		jumps, err := store.IngestLog(p, log)
+		if err != nil {
+			return err
+		}
+		fmt.Println("parsed:", log, len(jumps), "new jumps")
+	}
+	return store.Save()
+}
+
+// warnUnknownSystem is the gamelog parsers' Warn, a log naming a system the graph lacks likely means graph.json is outdated.
+func warnUnknownSystem(err error) {
+	logger.Println("warning:", err)
+}
+
+// findGamelogs locates the Gamelogs directory and fills in an empty character with the listener of the most recent log.
+// Not finding it is only an error if it was configured or required, dir is empty then.
+func findGamelogs(configured string, character *string, required bool) (dir string, err error) {
+	dir, err = gamelog.FindDir(configured)
+	if err != nil {
+		if configured != "" || required {
+			return "", err
+		}
+		return "", nil
+	}
+	if *character == "" {
+		*character, err = gamelog.LatestListener(dir)
+		if err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// characterLogs returns the logs of character in dir, oldest first, none if dir is empty.
+func characterLogs(dir, character string) ([]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	files, err := gamelog.List(dir, character)
+	if err != nil {
+		return nil, err
+	}
+	logs := make([]string, len(files))
+	for i, f := range files {
+		logs[i] = f.Path
+	}
+	return logs, nil
+}
+
+// lookupSystem accepts a system ID or a case-insensitive name.
+func lookupSystem(g universe.Graph, arg string) (uint32, error) {
+	id, ok := g.Lookup(arg)
+	if !ok {
+		return 0, fmt.Errorf("unknown system %q", arg)
+	}
+	return id, nil
+}
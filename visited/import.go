@@ -0,0 +1,157 @@
+package visited
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"eve-tour/universe"
+)
+
+const (
+	ESISource      = "esi"
+	KillmailSource = "killmail"
+)
+
+// Killmail holds the fields of an ESI killmail, zKillboard exports embed the same ones.
+type Killmail struct {
+	ID       uint32    `json:"killmail_id"`
+	Time     time.Time `json:"killmail_time"`
+	SystemID uint32    `json:"solar_system_id"`
+	Victim   struct {
+		CharacterID uint32 `json:"character_id"`
+	} `json:"victim"`
+	Attackers []struct {
+		CharacterID uint32 `json:"character_id"`
+	} `json:"attackers"`
+}
+
+// Involves reports whether character died or got on the mail.
+func (k Killmail) Involves(character uint32) bool {
+	if k.Victim.CharacterID == character {
+		return true
+	}
+	for _, a := range k.Attackers {
+		if a.CharacterID == character {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadKillmails decodes a JSON array of killmails or a stream of killmail objects, one per line like the bulk dumps.
+func ReadKillmails(r io.Reader) ([]Killmail, error) {
+	br := bufio.NewReaderSize(r, 1024*1024*32)
+	first, err := firstNonSpace(br)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading killmails: %w", err)
+	}
+
+	d := json.NewDecoder(br)
+	if first == '[' {
+		var kms []Killmail
+		err = d.Decode(&kms)
+		if err != nil {
+			return nil, fmt.Errorf("decoding killmails: %w", err)
+		}
+		return kms, nil
+	}
+
+	var kms []Killmail
+	for {
+		var km Killmail
+		err = d.Decode(&km)
+		if err == io.EOF {
+			return kms, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding killmail %d: %w", len(kms)+1, err)
+		}
+		kms = append(kms, km)
+	}
+}
+
+func firstNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, r.UnreadByte()
+		}
+	}
+}
+
+// ImportKillmails marks the systems of the killmails in path, only the ones involving character unless it is 0.
+// Mails without a system, like zKillboard API listings that only carry IDs and hashes, or in a system missing from g are skipped and counted.
+func (s *Store) ImportKillmails(g universe.Graph, path string, character uint32) (imported, skipped int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("opening killmails: %w", err)
+	}
+	defer f.Close()
+
+	kms, err := ReadKillmails(f)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, km := range kms {
+		if _, ok := g.Nodes[km.SystemID]; !ok {
+			skipped++
+			continue
+		}
+		if character != 0 && !km.Involves(character) {
+			continue
+		}
+		s.Mark(km.SystemID, km.Time.UTC(), KillmailSource)
+		imported++
+	}
+	return imported, skipped, nil
+}
+
+// ImportList marks the systems listed in path, one name or ID per line, # starts a comment.
+// The file's modification time is used as visit time.
+func (s *Store) ImportList(g universe.Graph, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("opening list: %w", err)
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("stating list: %w", err)
+	}
+	at := st.ModTime().UTC()
+	source := "list:" + filepath.Base(path)
+
+	var n, line int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line++
+		name, _, _ := strings.Cut(scanner.Text(), "#")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := g.Lookup(name)
+		if !ok {
+			return n, fmt.Errorf("%s:%d: unknown system %q", path, line, name)
+		}
+		s.Mark(id, at, source)
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return n, fmt.Errorf("reading list: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,96 @@
+package visited
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"eve-tour/universe"
+)
+
+func TestReadKillmails(t *testing.T) {
+	tests := []struct {
+		name, input string
+		ids         []uint32
+		err         string
+	}{
+		{name: "array", input: ` [{"killmail_id": 1}, {"killmail_id": 2}]`, ids: []uint32{1, 2}},
+		{name: "stream", input: "{\"killmail_id\": 1}\n{\"killmail_id\": 2}\n", ids: []uint32{1, 2}},
+		{name: "empty", input: "\n"},
+		{name: "broken stream", input: "{\"killmail_id\": 1}\n{\"killmail_id\": \"two\"}\n", err: "killmail 2"},
+		{name: "broken array", input: `[{"killmail_id": 1},`, err: "decoding killmails"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kms, err := ReadKillmails(strings.NewReader(tt.input))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got %v, want an error about %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []uint32
+			for _, km := range kms {
+				ids = append(ids, km.ID)
+			}
+			if !slices.Equal(ids, tt.ids) {
+				t.Errorf("read killmails %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestImportKillmails(t *testing.T) {
+	g := universe.Graph{Nodes: map[uint32]universe.System{
+		1: {Name: "Jita"},
+		2: {Name: "Perimeter"},
+		3: {Name: "Urlen"},
+	}}
+	// the last two have no system, or one missing from the graph
+	mails := `{"killmail_id": 1, "killmail_time": "2026-10-18T12:00:00+02:00", "solar_system_id": 1, "victim": {"character_id": 7}}
+{"killmail_id": 2, "killmail_time": "2026-10-18T13:00:00Z", "solar_system_id": 2, "victim": {"character_id": 8}, "attackers": [{"character_id": 7}]}
+{"killmail_id": 3, "killmail_time": "2026-10-18T14:00:00Z", "solar_system_id": 3, "victim": {"character_id": 8}}
+{"killmail_id": 4}
+{"killmail_id": 5, "killmail_time": "2026-10-18T15:00:00Z", "solar_system_id": 31000005, "victim": {"character_id": 7}}
+`
+	path := filepath.Join(t.TempDir(), "kills.jsonl")
+	err := os.WriteFile(path, []byte(mails), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Store{Systems: make(map[uint32]Visit)}
+	imported, skipped, err := s.ImportKillmails(g, path, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 2 || skipped != 2 {
+		t.Errorf("imported %d and skipped %d, want 2 each", imported, skipped)
+	}
+	if _, ok := s.Systems[3]; ok || len(s.Systems) != 2 {
+		t.Errorf("marked %+v, want Jita and Perimeter where 7 was involved", s.Systems)
+	}
+	jita := s.Systems[1]
+	if want := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC); !jita.FirstSeen.Equal(want) || jita.FirstSeen.Location() != time.UTC {
+		t.Errorf("Jita first seen %v, want %v", jita.FirstSeen, want)
+	}
+	if !slices.Equal(jita.Sources, []string{KillmailSource}) {
+		t.Errorf("Jita's sources are %v", jita.Sources)
+	}
+
+	imported, _, err = s.ImportKillmails(g, path, 0)
+	if err != nil || imported != 3 {
+		t.Errorf("importing every character's mails got %d, %v, want 3", imported, err)
+	}
+
+	_, _, err = s.ImportKillmails(g, filepath.Join(t.TempDir(), "missing.json"), 0)
+	if err == nil {
+		t.Error("no error importing a missing file")
+	}
+}
@@ -0,0 +1,252 @@
+// Package visited remembers which systems a character has been to, across runs.
+package visited
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"eve-tour/gamelog"
+)
+
+const ManualSource = "manual"
+
+// Visit is what we know about one visited system.
+type Visit struct {
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Sources are where the visit was learned from: "manual", "esi", "killmail" or "log:" followed by the Gamelogs directory.
+	Sources []string
+}
+
+// LogState remembers how much of a log file was already ingested.
+type LogState struct {
+	Offset int64
+}
+
+// Store is the persisted visited set of one character.
+type Store struct {
+	Character string
+	Systems   map[uint32]Visit
+	// Logs is keyed by absolute path.
+	Logs map[string]LogState
+
+	path string
+}
+
+// FileName returns where the store of character is kept.
+func FileName(character string) string {
+	if character == "" {
+		character = "default"
+	}
+	safe := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, character)
+	return "visited-" + safe + ".json"
+}
+
+// Open loads the store of character, a missing file is an empty store.
+func Open(character string) (*Store, error) {
+	s := &Store{
+		Character: character,
+		Systems:   make(map[uint32]Visit),
+		Logs:      make(map[string]LogState),
+		path:      FileName(character),
+	}
+
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening visited store: %w", err)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("decoding visited store: %w", err)
+	}
+	if s.Systems == nil {
+		s.Systems = make(map[uint32]Visit)
+	}
+	if s.Logs == nil {
+		s.Logs = make(map[string]LogState)
+	}
+	s.tidy()
+	return s, nil
+}
+
+// logSource is the source of the visits read from the logs in dir.
+// It is the directory rather than the log, or sources would grow by one every session.
+func logSource(dir string) string {
+	return "log:" + dir
+}
+
+// tidy forgets the logs deleted since they were ingested,
+// and moves the sources of stores saved when each log was its own source to the log's directory.
+func (s *Store) tidy() {
+	dirs := make(map[string]string, len(s.Logs))
+	for path := range s.Logs {
+		dirs[filepath.Base(path)] = filepath.Dir(path)
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			delete(s.Logs, path)
+		}
+	}
+	for id, v := range s.Systems {
+		sources := v.Sources[:0]
+		for _, source := range v.Sources {
+			if name, ok := strings.CutPrefix(source, "log:"); ok && !filepath.IsAbs(name) {
+				source = "log" // ingested before we kept the directory
+				if dir, ok := dirs[name]; ok {
+					source = logSource(dir)
+				}
+			}
+			if !slices.Contains(sources, source) {
+				sources = append(sources, source)
+			}
+		}
+		v.Sources = sources
+		s.Systems[id] = v
+	}
+}
+
+// Save writes the store atomically, a crash mid-write can't lose months of history.
+func (s *Store) Save() error {
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("creating visited store: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	w := bufio.NewWriter(f)
+	err = json.NewEncoder(w).Encode(s)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("writing visited store: %w", err)
+	}
+
+	err = os.Rename(tmp, s.path)
+	if err != nil {
+		return fmt.Errorf("replacing visited store: %w", err)
+	}
+	return nil
+}
+
+// Mark records a visit of system at the given time.
+func (s *Store) Mark(system uint32, at time.Time, source string) {
+	v, ok := s.Systems[system]
+	if !ok || at.Before(v.FirstSeen) {
+		v.FirstSeen = at
+	}
+	if at.After(v.LastSeen) {
+		v.LastSeen = at
+	}
+	if !slices.Contains(v.Sources, source) {
+		v.Sources = append(v.Sources, source)
+	}
+	s.Systems[system] = v
+}
+
+func (s *Store) Unmark(system uint32) {
+	delete(s.Systems, system)
+}
+
+// Reset forgets every visit and every ingested log.
+func (s *Store) Reset() {
+	clear(s.Systems)
+	clear(s.Logs)
+}
+
+// Set returns the visited systems in the shape route.Filter expects.
+func (s *Store) Set() map[uint32]struct{} {
+	set := make(map[uint32]struct{}, len(s.Systems))
+	for id := range s.Systems {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+// IngestLog reads the part of a gamelog not seen yet and returns the jumps it contained.
+// A trailing line without a newline is left for next time, the client may still be writing it.
+func (s *Store) IngestLog(p *gamelog.Parser, path string) ([]gamelog.Jump, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolving log path: %w", err)
+	}
+
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, fmt.Errorf("opening log file: %w", err)
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stating log file: %w", err)
+	}
+
+	state := s.Logs[abs]
+	if st.Size() < state.Offset {
+		state.Offset = 0 // truncated or replaced, start over
+	}
+	if state.Offset == st.Size() {
+		return nil, nil
+	}
+
+	var head [2]byte
+	_, err = f.ReadAt(head[:], 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading log file: %w", err)
+	}
+	_, err = f.Seek(state.Offset, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("seeking log file: %w", err)
+	}
+
+	source := logSource(filepath.Dir(abs))
+	fallback := st.ModTime().UTC()
+	var jumps []gamelog.Jump
+	r := gamelog.NewReader(f, gamelog.DetectEncoding(head[:]))
+	for {
+		line, n, err := r.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return jumps, fmt.Errorf("reading log file: %w", err)
+		}
+		state.Offset += int64(n)
+
+		j, ok := p.ParseLine(line)
+		if !ok {
+			continue
+		}
+		if j.Time.IsZero() {
+			j.Time = fallback
+		}
+		s.Mark(j.From, j.Time, source)
+		s.Mark(j.To, j.Time, source)
+		jumps = append(jumps, j)
+	}
+
+	s.Logs[abs] = state
+	return jumps, nil
+}
@@ -0,0 +1,100 @@
+package visited
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"eve-tour/gamelog"
+	"eve-tour/universe"
+)
+
+func TestIngestLogResumes(t *testing.T) {
+	full, err := os.ReadFile(filepath.Join("..", "gamelog", "testdata", "utf16le-bom.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := gamelog.NewParser(universe.Graph{Nodes: map[uint32]universe.System{
+		1: {Name: "Jita"},
+		2: {Name: "Perimeter"},
+		3: {Name: "Urlen"},
+	}})
+	dir := t.TempDir()
+	path := filepath.Join(dir, "20261018_120000.txt")
+
+	// the client wrote half the second jump line, then the rest
+	cut := len(full) - 11
+	var got []gamelog.Jump
+	s := &Store{Systems: make(map[uint32]Visit), Logs: make(map[string]LogState)}
+	for _, b := range [][]byte{full[:cut], full} {
+		err = os.WriteFile(path, b, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jumps, err := s.IngestLog(p, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, jumps...)
+	}
+	jumps, err := s.IngestLog(p, path)
+	if err != nil || len(jumps) != 0 {
+		t.Errorf("ingesting an unchanged log again got %v, %v", jumps, err)
+	}
+
+	var route []uint32
+	for _, j := range got {
+		route = append(route, j.From, j.To)
+	}
+	if want := []uint32{1, 2, 2, 3}; !slices.Equal(route, want) {
+		t.Errorf("jumped through %v, want %v once each", route, want)
+	}
+	if len(s.Systems) != 3 || !slices.Equal(s.Systems[3].Sources, []string{logSource(dir)}) {
+		t.Errorf("marked %+v", s.Systems)
+	}
+}
+
+func TestOpenTidiesLogs(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	logs := filepath.Join(dir, "Gamelogs")
+	err := os.Mkdir(logs, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := filepath.Join(logs, "20261018_120000.txt")
+	err = os.WriteFile(kept, nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted := filepath.Join(logs, "20261017_120000.txt")
+
+	s, err := Open("Test Pilot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Logs[kept] = LogState{Offset: 10}
+	s.Logs[deleted] = LogState{Offset: 20}
+	// saved before sources were directories
+	s.Systems[1] = Visit{Sources: []string{"log:20261017_120000.txt", ManualSource, "log:20261018_120000.txt"}}
+	s.Systems[2] = Visit{Sources: []string{"log:20200101_000000.txt"}}
+	err = s.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open("Test Pilot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Logs[deleted]; ok || len(s.Logs) != 1 {
+		t.Errorf("logs %v, want only %s", s.Logs, kept)
+	}
+	if want := []string{logSource(logs), ManualSource}; !slices.Equal(s.Systems[1].Sources, want) {
+		t.Errorf("sources %v, want %v", s.Systems[1].Sources, want)
+	}
+	if want := []string{"log"}; !slices.Equal(s.Systems[2].Sources, want) {
+		t.Errorf("sources of an unknown log %v, want %v", s.Systems[2].Sources, want)
+	}
+}
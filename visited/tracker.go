@@ -0,0 +1,52 @@
+package visited
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"eve-tour/esi"
+)
+
+// Track polls the location of the logged in character every interval and marks the systems it is seen in, saving after each new one.
+// It is a fallback for players without logs and misses systems flown through between two polls.
+// Failed polls are reported to log, nil keeps quiet. onMove, if not nil, is called on every system change.
+func (s *Store) Track(ctx context.Context, session *esi.Session, interval time.Duration, log *log.Logger, onMove func(system uint32)) error {
+	if log == nil {
+		log = discard
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last uint32
+	for {
+		token, character, err := session.Token()
+		if err != nil {
+			return err
+		}
+		system, err := session.Client.Location(token, character)
+		if err != nil {
+			// Transient ESI hiccups shouldn't end hours of tracking.
+			log.Println("failed to get location:", err)
+		} else if system != last {
+			last = system
+			s.Mark(system, time.Now().UTC(), ESISource)
+			err = s.Save()
+			if err != nil {
+				return err
+			}
+			if onMove != nil {
+				onMove(system)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+var discard = log.New(io.Discard, "", 0)
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"eve-tour/distance"
+	"eve-tour/esi"
+	"eve-tour/gamelog"
+	"eve-tour/route"
+	"eve-tour/universe"
+	"eve-tour/visited"
+)
+
+// watcher tails the gamelogs of one character, new session files included.
+type watcher struct {
+	dir, character string
+	interval       time.Duration
+	store          *visited.Store
+	parser         *gamelog.Parser
+
+	// files are the logs of character seen so far, others remembers the logs of other characters so their headers are only read once.
+	files  []string
+	others map[string]struct{}
+}
+
+func newWatcher(g universe.Graph, store *visited.Store, dir, character string, interval time.Duration) (*watcher, error) {
+	p := gamelog.NewParser(g)
+	p.Warn = warnUnknownSystem
+	return &watcher{
+		dir:       dir,
+		character: character,
+		interval:  interval,
+		store:     store,
+		parser:    p,
+		others:    make(map[string]struct{}),
+	}, nil
+}
+
+// scan picks up log files created since the last call, only their headers are read.
+func (w *watcher) scan() error {
+	paths, err := gamelog.Paths(w.dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if slices.Contains(w.files, path) {
+			continue
+		}
+		if _, ok := w.others[path]; ok {
+			continue
+		}
+		log, err := gamelog.ReadHeader(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case log.Listener == "":
+			// the client did not write the header yet, look again next time
+		case strings.EqualFold(log.Listener, w.character):
+			w.files = append(w.files, log.Path)
+		default:
+			w.others[log.Path] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// poll returns the jumps written since the last call, oldest first, and saves the store if there were any.
+func (w *watcher) poll() ([]gamelog.Jump, error) {
+	err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	var jumps []gamelog.Jump
+	for _, log := range w.files {
+		j, err := w.store.IngestLog(w.parser, log)
+		if err != nil {
+			return nil, err
+		}
+		jumps = append(jumps, j...)
+	}
+	if len(jumps) == 0 {
+		return nil, nil
+	}
+	slices.SortStableFunc(jumps, func(a, b gamelog.Jump) int {
+		return a.Time.Compare(b.Time)
+	})
+	return jumps, w.store.Save()
+}
+
+// follow keeps the route up to date while the pilot flies it.
+// Visited targets are dropped as the logs report them and the rest is solved again from the current system whenever a jump leads away from the next target.
+// constraints still apply to what's left when planning again, with lkh.
+// Systems off the graph, wormholes or regions that weren't crawled, are waited out, planning again once back.
+func follow[T distance.Weight](session *esi.Session, g universe.Graph, full distance.Matrix[T], remaining []uint32, constraints []route.Constraint, lkh route.Solver, w *watcher) error {
+	if len(remaining) == 0 {
+		return nil
+	}
+	fmt.Println("watching", w.dir, "for", w.character, "jumps")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		jumps, err := w.poll()
+		if err != nil {
+			return fmt.Errorf("failed to read gamelogs: %w", err)
+		}
+		if len(jumps) == 0 {
+			continue
+		}
+
+		from, current := jumps[0].From, jumps[len(jumps)-1].To
+		next := remaining[0]
+		remaining = slices.DeleteFunc(remaining, func(id uint32) bool {
+			_, ok := w.store.Systems[id]
+			return ok
+		})
+		fmt.Println("in", systemName(g, current)+",", len(remaining), "systems left")
+		if len(remaining) == 0 {
+			fmt.Println("tour complete!")
+			return nil
+		}
+
+		if remaining[0] != next {
+			continue // reached the next target, the autopilot moves on by itself
+		}
+		currentIndex, ok := g.IdsToMatrixIndexes[current]
+		if !ok {
+			fmt.Println("off the graph, waiting to get back on it")
+			continue
+		}
+		if fromIndex, ok := g.IdsToMatrixIndexes[from]; ok {
+			nextIndex := g.IdsToMatrixIndexes[next]
+			if full.At(currentIndex, nextIndex) < full.At(fromIndex, nextIndex) {
+				continue // on the way
+			}
+		}
+
+		fmt.Println("off route, planning again from", g.Nodes[current].Name)
+		problem := route.NewWeightedProblem(g, full, remaining)
+		firstHopCosts, err := problem.FirstHopCosts(g, current)
+		if err != nil {
+			return err
+		}
+		// warnings were given on the first plan
+		precedences, err := problem.Precedences(g, constraints, nil)
+		if err != nil {
+			return err
+		}
+		// the rest of the route is still a good start
+		initial, _ := problem.Seed(g, problem.LastTour(), firstHopCosts)
+		remaining, err = route.SolveSOP(context.Background(), lkh, problem, firstHopCosts, precedences, initial)
+		if err != nil {
+			return fmt.Errorf("failed to solve SOP: %w", err)
+		}
+		err = writeOutput(g, remaining, nil)
+		if err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+
+		token, _, err := session.Token()
+		if err != nil {
+			return err
+		}
+		err = session.Client.AddWaypoints(token, remaining)
+		if err != nil {
+			return fmt.Errorf("failed to add waypoints to UI: %w", err)
+		}
+	}
+	return nil
+}
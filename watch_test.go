@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"eve-tour/distance"
+	"eve-tour/route"
+	"eve-tour/universe"
+	"eve-tour/visited"
+)
+
+func TestFollowWaitsOffGraph(t *testing.T) {
+	// Niarja was crawled but is out of gate range, in Pochven
+	const jita, perimeter, niarja = 30000142, 30000144, 30003504
+	g := universe.Graph{
+		Nodes:              map[uint32]universe.System{jita: {Name: "Jita"}, perimeter: {Name: "Perimeter"}, niarja: {Name: "Niarja"}},
+		IdsToMatrixIndexes: map[uint32]uint{jita: 0, perimeter: 1},
+		MatrixIndexesToIds: []uint32{jita, perimeter},
+	}
+	full := distance.New[uint8](2)
+	full.Set(0, 1, 1)
+	full.Set(1, 0, 1)
+
+	t.Chdir(t.TempDir())
+	store, err := visited.Open("Test Pilot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Mkdir("Gamelogs", 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join("Gamelogs", "20261018_120000.txt")
+	header := "------------------------------------------------------------\n" +
+		"  Gamelog\n" +
+		"  Listener: Test Pilot\n" +
+		"  Session Started: 2026.10.18 12:00:00\n" +
+		"------------------------------------------------------------\n"
+	lines := header + "[ 2026.10.18 12:01:00 ] (None) Jumping from Jita to Niarja\n"
+	err = os.WriteFile(path, []byte(lines), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := newWatcher(g, store, "Gamelogs", "Test Pilot", 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- follow(nil, g, full, []uint32{perimeter}, nil, route.LKH, w)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	lines += "[ 2026.10.18 12:02:00 ] (None) Jumping from Niarja to Perimeter\n"
+	err = os.WriteFile(path, []byte(lines), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("still following after reaching the last target")
+	}
+}